@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+/*
+ * Sprite sheet animations. A sprite sheet is an image of equally sized frames with a row for each
+ * of its named animations. An Animation plays one of them at a time, timed by the game clock, so a
+ * level switches animations by state and draws the current frame.
+ */
+
+// AnimationSpec is where an animation is on its sprite sheet and how it plays
+type AnimationSpec struct {
+	row       int           // row of the sheet the frames are in, from the top
+	frames    int           // number of frames
+	frameTime time.Duration // each frame is shown for, a tick if 0
+	loop      bool          // start again after the last frame, otherwise hold it
+}
+
+// SpriteSheet is an image of animation frames
+type SpriteSheet struct {
+	image                   *ebiten.Image
+	frameWidth, frameHeight int
+	animations              map[string]AnimationSpec
+}
+
+// NewSpriteSheet returns a sheet of the named animations in image
+func NewSpriteSheet(image *ebiten.Image, frameWidth, frameHeight int, animations map[string]AnimationSpec) *SpriteSheet {
+	return &SpriteSheet{image: image, frameWidth: frameWidth, frameHeight: frameHeight, animations: animations}
+}
+
+// loadSpriteSheet loads a sheet of the named animations from path
+func loadSpriteSheet(path string, frameWidth, frameHeight int, animations map[string]AnimationSpec) *SpriteSheet {
+	return NewSpriteSheet(loadImage(path), frameWidth, frameHeight, animations)
+}
+
+// Frame returns the image of frame i of the named animation
+func (s *SpriteSheet) Frame(name string, i int) *ebiten.Image {
+	spec, ok := s.animations[name]
+	if !ok {
+		log.Fatalf("Unknown animation %q", name)
+	}
+	x, y := i*s.frameWidth, spec.row*s.frameHeight
+	return s.image.SubImage(image.Rect(x, y, x+s.frameWidth, y+s.frameHeight)).(*ebiten.Image)
+}
+
+// Animation plays the animations of a sprite sheet
+type Animation struct {
+	sheet *SpriteSheet
+	name  string
+	start time.Duration
+}
+
+// NewAnimation returns an animation playing the named animation of sheet from now
+func NewAnimation(sheet *SpriteSheet, name string, now time.Duration) *Animation {
+	return &Animation{sheet: sheet, name: name, start: now}
+}
+
+// Play switches to the named animation from its first frame, an animation already playing
+// carries on
+func (a *Animation) Play(name string, now time.Duration) {
+	if name != a.name {
+		a.name = name
+		a.start = now
+	}
+}
+
+// Restart plays the named animation from its first frame, even if it is already playing
+func (a *Animation) Restart(name string, now time.Duration) {
+	a.name = name
+	a.start = now
+}
+
+// Playing returns the name of the animation playing
+func (a *Animation) Playing() string {
+	return a.name
+}
+
+// Done returns true once an animation that does not loop has shown its last frame for its time
+func (a *Animation) Done(now time.Duration) bool {
+	spec := a.sheet.animations[a.name]
+	return !spec.loop && now-a.start >= time.Duration(spec.frames)*spec.shownFor()
+}
+
+// Image returns the frame of the animation to draw at now
+func (a *Animation) Image(now time.Duration) *ebiten.Image {
+	return a.sheet.Frame(a.name, animationFrame(a.sheet.animations[a.name], now-a.start))
+}
+
+// Size returns the width and height of the frames
+func (a *Animation) Size() (int, int) {
+	return a.sheet.frameWidth, a.sheet.frameHeight
+}
+
+// shownFor returns how long each frame is shown for
+func (spec AnimationSpec) shownFor() time.Duration {
+	if spec.frameTime <= 0 {
+		return tick
+	}
+	return spec.frameTime
+}
+
+// animationFrame returns the frame shown elapsed game time after an animation starts
+func animationFrame(spec AnimationSpec, elapsed time.Duration) int {
+	i := int(max(elapsed, 0) / spec.shownFor())
+	if spec.loop {
+		return i % spec.frames
+	}
+	return min(i, spec.frames-1)
+}
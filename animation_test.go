@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnimationFrame(t *testing.T) {
+	ms := time.Millisecond
+	looping := AnimationSpec{frames: 4, frameTime: 100 * ms, loop: true}
+	once := AnimationSpec{frames: 3, frameTime: 50 * ms}
+	tests := []struct {
+		name    string
+		spec    AnimationSpec
+		elapsed time.Duration
+		want    int
+	}{
+		{"First frame", looping, 0, 0},
+		{"Still the first frame", looping, 99 * ms, 0},
+		{"Second frame", looping, 100 * ms, 1},
+		{"Last frame", looping, 399 * ms, 3},
+		{"Loops to the start", looping, 400 * ms, 0},
+		{"Before the start", looping, -50 * ms, 0},
+		{"Plays once", once, 120 * ms, 2},
+		{"Holds the last frame", once, time.Second, 2},
+		{"No frame time shows a frame a tick", AnimationSpec{frames: 2, loop: true}, 3 * tick, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := animationFrame(tt.spec, tt.elapsed); got != tt.want {
+				t.Errorf("animationFrame() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnimationDone(t *testing.T) {
+	ms := time.Millisecond
+	sheet := NewSpriteSheet(nil, 10, 10, map[string]AnimationSpec{
+		"once": {frames: 3, frameTime: 50 * ms},
+		"loop": {frames: 3, frameTime: 50 * ms, loop: true},
+	})
+	a := NewAnimation(sheet, "once", time.Second)
+	if a.Done(time.Second + 149*ms) {
+		t.Error("Done() = true before the last frame has been shown")
+	}
+	if !a.Done(time.Second + 150*ms) {
+		t.Error("Done() = false after the last frame has been shown")
+	}
+	a.Play("loop", 1200*ms)
+	if a.Done(10 * time.Second) {
+		t.Error("Done() = true for a looping animation")
+	}
+	a.Play("loop", 2*time.Second)
+	if a.start != 1200*ms {
+		t.Errorf("Play() of the animation playing restarted it at %v", a.start)
+	}
+}
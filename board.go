@@ -0,0 +1,384 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+)
+
+/*
+ * Board holds the rules of the gems levels without any drawing. Gems are deleted, the gems
+ * below collapse up to fill the gaps and new gems are spawned at the bottom to refill the board.
+ * Changes that move gems return BoardEvents, which a level turns into animations.
+ *
+ * Big matches leave a special gem behind. When a special gem is cleared, by a match or by being
+ * deleted, it blasts a larger area of the board, setting off any special gems in that area too.
+ */
+
+// GemKind is what a gem does when it is cleared
+type GemKind int
+
+const (
+	GemNormal     GemKind = iota
+	GemLineClear          // clears its row, made by a run of 4
+	GemBomb               // clears the squares around it, made by an L or T
+	GemColourBomb         // clears every gem of its colour, made by a run of 5
+)
+
+// BoardEventKind is what happened to a gem on the board
+type BoardEventKind int
+
+const (
+	GemMatched  BoardEventKind = iota // the gem was part of a match and was removed
+	GemFell                           // the gem moved up to fill an empty square
+	GemSpawned                        // a new gem was created to fill an empty square
+	GemDeleted                        // the gem was deleted by the player
+	GemBlasted                        // the gem was cleared by a special gem
+	GemPromoted                       // the gem became a special gem
+)
+
+// BoardEvent records a change to one square of the board.
+type BoardEvent struct {
+	kind BoardEventKind
+	gem  int
+	from Coord // where the gem came from, below the board for a spawned gem
+	to   Coord // the square the gem is now in, or was removed from when matched
+}
+
+// BoardRange is the squares from one position to another in reading order, as a visual selection
+// of text is, so a range over several rows covers the end of the first row and the start of the last.
+type BoardRange struct {
+	from, to Coord
+}
+
+// Cells returns the squares in the range on a board of the given width.
+func (r BoardRange) Cells(columns int) []Coord {
+	var cells []Coord
+	start, end := highLow(r.from, r.to)
+	startX := start.x
+	for y := start.y; y <= end.y; y++ {
+		for x := startX; x < columns; x++ {
+			cells = append(cells, Coord{x, y})
+			if x == end.x && y == end.y {
+				break
+			}
+		}
+		// start next line at left edge
+		startX = 0
+	}
+	return cells
+}
+
+// joinOverflow is what JoinRows does with the gems pushed off the left of the row by the gems joined to it
+type joinOverflow int
+
+const (
+	joinDiscard joinOverflow = iota // the pushed off gems are lost and the row below is deleted
+	joinWrap                        // the pushed off gems take the place of the gems pulled up
+)
+
+// Board is a grid of gems numbered from 0 to numGems-1, with emptyGem for an empty square.
+// The kind of each gem is kept in a grid alongside.
+type Board struct {
+	gems    Grid[int]
+	kinds   Grid[GemKind]
+	numGems int
+	rng     *rand.Rand
+}
+
+// NewBoard creates an empty board. New gems are chosen with rng.
+func NewBoard(columns, rows, numGems int, rng *rand.Rand) *Board {
+	b := &Board{
+		gems:    NewGrid[int](columns, rows),
+		kinds:   NewGrid[GemKind](columns, rows),
+		numGems: numGems,
+		rng:     rng,
+	}
+	b.gems.SetAll(emptyGem)
+	return b
+}
+
+// Copy returns a copy of the board, for trying a change before making it. The copy shares the RNG.
+func (b *Board) Copy() *Board {
+	return &Board{gems: b.gems.Copy(), kinds: b.kinds.Copy(), numGems: b.numGems, rng: b.rng}
+}
+
+func (b *Board) Columns() int {
+	return b.gems.NumColumns()
+}
+
+func (b *Board) Rows() int {
+	return b.gems.NumRows()
+}
+
+func (b *Board) Gem(p Coord) int {
+	return b.gems.Get(p)
+}
+
+// SetGem puts a normal gem at p
+func (b *Board) SetGem(p Coord, gem int) {
+	b.gems.Set(p, gem)
+	b.kinds.Set(p, GemNormal)
+}
+
+func (b *Board) Kind(p Coord) GemKind {
+	return b.kinds.Get(p)
+}
+
+func (b *Board) SetKind(p Coord, kind GemKind) {
+	b.kinds.Set(p, kind)
+}
+
+// moveGem moves the gem and its kind from one square to another, leaving the first empty
+func (b *Board) moveGem(from, to Coord) {
+	b.gems.Set(to, b.gems.Get(from))
+	b.kinds.Set(to, b.kinds.Get(from))
+	b.SetGem(from, emptyGem)
+}
+
+// RowRange returns the range covering n whole rows from row y, limited to the bottom of the board.
+func (b *Board) RowRange(y, n int) BoardRange {
+	return BoardRange{Coord{0, y}, Coord{b.Columns() - 1, min(y+n, b.Rows()) - 1}}
+}
+
+// FillRandom fills the entire board with random gems.
+func (b *Board) FillRandom() {
+	for y := range b.Rows() {
+		for x := range b.Columns() {
+			b.SetGem(Coord{x, y}, b.rng.Intn(b.numGems))
+		}
+	}
+}
+
+// Delete empties the squares in the range. Special gems in the range blast the squares they clear.
+func (b *Board) Delete(r BoardRange) []BoardEvent {
+	return b.clear(r.Cells(b.Columns()), GemDeleted)
+}
+
+// blast returns the squares cleared by the special gem at p, which include p
+func (b *Board) blast(p Coord) []Coord {
+	var cells []Coord
+	switch b.kinds.Get(p) {
+	case GemLineClear:
+		cells = b.RowRange(p.y, 1).Cells(b.Columns())
+	case GemBomb:
+		for y := max(p.y-1, 0); y <= min(p.y+1, b.Rows()-1); y++ {
+			for x := max(p.x-1, 0); x <= min(p.x+1, b.Columns()-1); x++ {
+				cells = append(cells, Coord{x, y})
+			}
+		}
+	case GemColourBomb:
+		colour := b.gems.Get(p)
+		b.gems.ForEach(func(q Coord, gem int) {
+			if gem == colour {
+				cells = append(cells, q)
+			}
+		})
+	default:
+		cells = []Coord{p}
+	}
+	return cells
+}
+
+// clear empties the squares, and the squares blasted by any special gems among them. The squares
+// asked for are reported as kind, the others as blasted.
+func (b *Board) clear(cells []Coord, kind BoardEventKind) []BoardEvent {
+	var events []BoardEvent
+	cleared := NewGridOfBools(b.Columns(), b.Rows())
+	for _, p := range cells {
+		cleared.Set(p, true)
+	}
+	queue := append([]Coord{}, cells...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, q := range b.blast(p) {
+			if !cleared.Get(q) {
+				cleared.Set(q, true)
+				queue = append(queue, q)
+			}
+		}
+	}
+	cleared.ForEach(func(p Coord, clear bool) {
+		if !clear || b.gems.Get(p) == emptyGem {
+			return
+		}
+		e := BoardEvent{kind: GemBlasted, gem: b.gems.Get(p), from: p, to: p}
+		if slices.Contains(cells, p) {
+			e.kind = kind
+		}
+		events = append(events, e)
+		b.SetGem(p, emptyGem)
+	})
+	return events
+}
+
+// Collapse moves the gems below each empty square up to fill it, leaving the empty squares at the bottom.
+func (b *Board) Collapse() []BoardEvent {
+	var events []BoardEvent
+	for x := range b.Columns() {
+		for y := range b.Rows() {
+			p := Coord{x, y}
+			if b.gems.Get(p) != emptyGem {
+				continue
+			}
+			below, ok := b.findGemBelow(p)
+			if !ok {
+				break
+			}
+			gem := b.gems.Get(below)
+			b.moveGem(below, p)
+			events = append(events, BoardEvent{kind: GemFell, gem: gem, from: below, to: p})
+		}
+	}
+	return events
+}
+
+// Refill spawns new gems in the empty squares. They come from beneath the bottom of the board.
+func (b *Board) Refill() []BoardEvent {
+	var events []BoardEvent
+	for x := range b.Columns() {
+		for y := range b.Rows() {
+			p := Coord{x, y}
+			if b.gems.Get(p) == emptyGem {
+				gem := b.rng.Intn(b.numGems)
+				b.SetGem(p, gem)
+				events = append(events, BoardEvent{kind: GemSpawned, gem: gem, from: Coord{x, b.Rows() + 1}, to: p})
+			}
+		}
+	}
+	return events
+}
+
+// FindMatches finds the gems in three or more identical gems in a row or column. The mask marks
+// the squares of the matched gems.
+func (b *Board) FindMatches() (bool, Grid[bool]) {
+	mask := NewGridOfBools(b.Columns(), b.Rows())
+	matches := b.Matches()
+	for _, m := range matches {
+		for _, p := range m.cells {
+			mask.Set(p, true)
+		}
+	}
+	return len(matches) > 0, mask
+}
+
+// RemoveMatches empties the squares of all matched gems. A match bigger than three leaves a
+// special gem of its colour behind.
+func (b *Board) RemoveMatches() []BoardEvent {
+	var cells []Coord
+	var promoted []Match
+	for _, m := range b.Matches() {
+		if m.Special() == GemNormal {
+			cells = append(cells, m.cells...)
+		} else {
+			promoted = append(promoted, m)
+			for _, p := range m.cells {
+				if p != m.Center() || b.kinds.Get(p) != GemNormal {
+					// a special gem in the match is set off rather than kept
+					cells = append(cells, p)
+				}
+			}
+		}
+	}
+	events := b.clear(cells, GemMatched)
+	for _, m := range promoted {
+		p := m.Center()
+		b.SetGem(p, m.gem)
+		b.kinds.Set(p, m.Special())
+		events = append(events, BoardEvent{kind: GemPromoted, gem: m.gem, from: p, to: p})
+	}
+	return events
+}
+
+// DeleteShiftLeft empties the squares in the range and moves the gems after it back to fill the
+// space, wrapping gems from the start of each row onto the end of the row above.
+func (b *Board) DeleteShiftLeft(r BoardRange) []BoardEvent {
+	var events []BoardEvent
+	start, end := highLow(r.from, r.to)
+	dest := b.gems.IndexOf(start)
+	src := b.gems.IndexOf(end) + 1
+	for i := dest; i <= b.gems.LastIndex(); i++ {
+		if src > b.gems.LastIndex() {
+			b.SetGem(b.gems.IndexToCoord(i), emptyGem)
+		} else {
+			gem := b.gems.GetAtIndex(src)
+			b.moveGem(b.gems.IndexToCoord(src), b.gems.IndexToCoord(i))
+			events = append(events, BoardEvent{kind: GemFell, gem: gem,
+				from: b.gems.IndexToCoord(src), to: b.gems.IndexToCoord(i)})
+		}
+		src++
+	}
+	return events
+}
+
+// ShiftRow rotates row y right by n columns, or left when n is negative. Gems shifted off one end
+// of the row wrap around to the other.
+func (b *Board) ShiftRow(y, n int) {
+	width := b.Columns()
+	row := slices.Clone(b.gems[y])
+	kinds := slices.Clone(b.kinds[y])
+	for x := range width {
+		to := Coord{((x+n)%width + width) % width, y}
+		b.gems.Set(to, row[x])
+		b.kinds.Set(to, kinds[x])
+	}
+}
+
+// JoinRows joins the first pulled gems of the row below y onto the end of row y, pushing the same
+// number of gems off its start. The overflow decides what happens to the pushed off gems and to
+// the rest of the row below.
+func (b *Board) JoinRows(y, pulled int, overflow joinOverflow) {
+	joinRow(b.gems, y, pulled, overflow)
+	joinRow(b.kinds, y, pulled, overflow)
+	if overflow == joinDiscard {
+		for x := range b.Columns() {
+			b.SetGem(Coord{x, y + 1}, emptyGem)
+		}
+		b.Collapse()
+	}
+}
+
+// joinRow joins the start of row y+1 of a grid onto the end of row y. With joinWrap the start of
+// row y goes onto the end of row y+1, otherwise row y+1 is left unchanged.
+func joinRow[T comparable](g Grid[T], y, pulled int, overflow joinOverflow) {
+	row := slices.Clone(g[y])
+	below := slices.Clone(g[y+1])
+	copy(g[y], append(row[pulled:], below[:pulled]...))
+	if overflow == joinWrap {
+		copy(g[y+1], append(below[pulled:], row[:pulled]...))
+	}
+}
+
+// findGemBelow finds the nearest gem below an empty square
+func (b *Board) findGemBelow(p Coord) (Coord, bool) {
+	for y := p.y; y < b.Rows(); y++ {
+		if b.gems.Get(Coord{p.x, y}) != emptyGem {
+			return Coord{p.x, y}, true
+		}
+	}
+	return Coord{-1, -1}, false // did not find a square with a gem
+}
+
+// highLow() returns the highest and lowest points in that order where highest is closest to the top left of the screen
+// disregard a point if it has negative values
+func highLow(p1, p2 Coord) (Coord, Coord) {
+	if p2.x < 0 || p2.y < 0 {
+		return p1, p2
+	}
+	if p1.x < 0 || p1.y < 0 {
+		return p2, p1
+	}
+	if p1.y == p2.y {
+		if p1.x >= p2.x {
+			return p2, p1
+		} else {
+			return p1, p2
+		}
+	} else {
+		if p1.y > p2.y {
+			return p2, p1
+		} else {
+			return p1, p2
+		}
+	}
+}
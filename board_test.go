@@ -0,0 +1,201 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// newTestBoard creates a board from rows of gem numbers, padding it to numGemRows rows
+// with gems that never match.
+func newTestBoard(rows [][]int) *Board {
+	b := NewBoard(len(rows[0]), numGemRows, 4, rand.New(rand.NewSource(1)))
+	for y := range b.Rows() {
+		for x := range b.Columns() {
+			gem := 10 + (x+y)%2 + 2*(y%2) // a checkerboard of gems that are not in the rows
+			if y < len(rows) {
+				gem = rows[y][x]
+			}
+			b.SetGem(Coord{x, y}, gem)
+		}
+	}
+	return b
+}
+
+// checkRows checks the first rows of the board hold the gems in want
+func checkRows(t *testing.T, b *Board, want [][]int) {
+	t.Helper()
+	for y, row := range want {
+		for x, gem := range row {
+			if got := b.Gem(Coord{x, y}); got != gem {
+				t.Errorf("row %d column %d = %d; want %d", y, x, got, gem)
+			}
+		}
+	}
+}
+
+func TestBoardRangeCells(t *testing.T) {
+	tests := []struct {
+		name string
+		r    BoardRange
+		want []Coord
+	}{
+		{"Single square", BoardRange{Coord{1, 1}, Coord{1, 1}}, []Coord{{1, 1}}},
+		{"Backwards on a row", BoardRange{Coord{2, 0}, Coord{0, 0}}, []Coord{{0, 0}, {1, 0}, {2, 0}}},
+		{"Across rows", BoardRange{Coord{2, 0}, Coord{1, 1}}, []Coord{{2, 0}, {3, 0}, {0, 1}, {1, 1}}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Cells(4); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Cells() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBoardFindMatches(t *testing.T) {
+	b := newTestBoard([][]int{
+		{0, 0, 1, 2},
+		{3, 4, 1, 6},
+		{3, 5, 1, 7},
+	})
+	found, mask := b.FindMatches()
+	if !found {
+		t.Fatalf("FindMatches() did not find the column of 1s")
+	}
+	for y := range b.Rows() {
+		for x := range b.Columns() {
+			if want := x == 2 && y < 3; mask.Get(Coord{x, y}) != want {
+				t.Errorf("mask at %d,%d = %v; want %v", x, y, !want, want)
+			}
+		}
+	}
+
+	b.SetGem(Coord{2, 1}, 0)
+	if found, _ := b.FindMatches(); found {
+		t.Errorf("FindMatches() found a match after it was broken")
+	}
+}
+
+func TestBoardDeleteCollapseRefill(t *testing.T) {
+	b := newTestBoard([][]int{
+		{0, 0, 3, 0},
+		{1, 2, 0, 4},
+	})
+	if found, _ := b.FindMatches(); found {
+		t.Fatalf("test board already has a match")
+	}
+
+	// deleting the 3 lets the 0 below it move up into the gap
+	b.Delete(BoardRange{Coord{2, 0}, Coord{2, 0}})
+	fell := b.Collapse()
+	checkRows(t, b, [][]int{{0, 0, 0, 0}})
+	if len(fell) != numGemRows-1 {
+		t.Errorf("Collapse() returned %d events; want %d", len(fell), numGemRows-1)
+	}
+	for _, e := range fell {
+		if e.kind != GemFell || e.from.x != 2 || e.from.y != e.to.y+1 {
+			t.Errorf("Collapse() event %+v is not a gem moving up one square", e)
+		}
+	}
+	bottom := Coord{2, numGemRows - 1}
+	if b.Gem(bottom) != emptyGem {
+		t.Errorf("bottom of the column was not left empty")
+	}
+
+	matched := b.RemoveMatches()
+	if len(matched) != 4 {
+		t.Errorf("RemoveMatches() returned %d events; want 4", len(matched))
+	}
+	b.Collapse()
+	spawned := b.Refill()
+	for _, e := range spawned {
+		if e.kind != GemSpawned || e.from.y != numGemRows+1 || b.Gem(e.to) != e.gem {
+			t.Errorf("Refill() event %+v is not a new gem from below the board", e)
+		}
+	}
+	b.gems.ForEach(func(p Coord, gem int) {
+		if gem == emptyGem {
+			t.Errorf("square %v is empty after Refill()", p)
+		}
+	})
+}
+
+func TestBoardCopy(t *testing.T) {
+	b := newTestBoard([][]int{{0, 0, 1, 2}})
+	c := b.Copy()
+	c.SetGem(Coord{2, 0}, 0)
+	if found, mask := c.FindMatches(); !found || !mask.Get(Coord{2, 0}) {
+		t.Errorf("replacing the third gem did not make a triple")
+	}
+	if b.Gem(Coord{2, 0}) != 1 {
+		t.Errorf("changing the copy modified the original board")
+	}
+}
+
+func TestBoardsOfDifferentSizes(t *testing.T) {
+	small := NewBoard(3, 4, 3, rand.New(rand.NewSource(1)))
+	large := NewBoard(9, 12, 6, rand.New(rand.NewSource(1)))
+	small.FillRandom()
+	large.FillRandom()
+	for _, b := range []*Board{small, large} {
+		b.Delete(b.RowRange(0, 2))
+		b.Collapse()
+		b.Refill()
+		b.FindMatches()
+		if got := len(b.RowRange(0, 100).Cells(b.Columns())); got != b.Columns()*b.Rows() {
+			t.Errorf("%dx%d board has %d squares", b.Columns(), b.Rows(), got)
+		}
+	}
+}
+
+func TestBoardShiftRow(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{1, []int{3, 0, 1, 2}},
+		{-1, []int{1, 2, 3, 0}},
+		{2, []int{2, 3, 0, 1}},
+		{4, []int{0, 1, 2, 3}},
+		{-5, []int{1, 2, 3, 0}},
+	}
+
+	for _, tt := range tests {
+		b := newTestBoard([][]int{
+			{0, 1, 2, 3},
+			{4, 5, 6, 7},
+		})
+		b.ShiftRow(0, tt.n)
+		checkRows(t, b, [][]int{tt.want, {4, 5, 6, 7}})
+	}
+}
+
+func TestBoardJoinRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow joinOverflow
+		want     [][]int
+	}{
+		{"Discard", joinDiscard, [][]int{{2, 3, 4, 5}, {8, 9, 8, 9}}},
+		{"Wrap", joinWrap, [][]int{{2, 3, 4, 5}, {6, 7, 0, 1}, {8, 9, 8, 9}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBoard([][]int{
+				{0, 1, 2, 3},
+				{4, 5, 6, 7},
+				{8, 9, 8, 9},
+			})
+			b.JoinRows(0, 2, tt.overflow)
+			checkRows(t, b, tt.want)
+			bottom := b.Gem(Coord{0, numGemRows - 1})
+			if tt.overflow == joinDiscard && bottom != emptyGem {
+				t.Errorf("bottom row was not left empty, got %d", bottom)
+			}
+			if tt.overflow == joinWrap && bottom == emptyGem {
+				t.Errorf("bottom row was emptied")
+			}
+		})
+	}
+}
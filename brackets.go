@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+/*
+ * LevelBrackets provides practice of % to jump between matching (), [] and {}.
+ * Each puzzle is a few lines of code with bracketed groups that must be deleted with d%
+ * to turn the buffer into the target.
+ */
+
+const (
+	bracketsLeft = 80
+	bracketsTop  = 100
+)
+
+var (
+	colorBracketMatch = darkButter
+	colorTargetText   = darkAluminium
+)
+
+var bracketPairs = map[byte]byte{
+	'(': ')', '[': ']', '{': '}',
+	')': '(', ']': '[', '}': '{',
+}
+
+var bracketsCommands = []commandSpec{
+	{keys: "h"}, {keys: "j"}, {keys: "k"}, {keys: "l"},
+	{keys: "0"}, {keys: "$"}, {keys: "%"}, {keys: "d%"}, {keys: "u"},
+	{keys: "f", argument: true},
+}
+
+// bracketPuzzle is a buffer and the text it must be changed into.
+type bracketPuzzle struct {
+	lines  []string
+	target []string
+}
+
+var bracketPuzzles = []bracketPuzzle{
+	{
+		lines:  []string{"print(debug(x))"},
+		target: []string{"print()"},
+	},
+	{
+		lines:  []string{"if (a && (b || c)) {", "}"},
+		target: []string{"if (a && ) {", "}"},
+	},
+	{
+		lines:  []string{"x = [1, [2, 3], {'a': (4, 5)}]"},
+		target: []string{"x = [1, , {'a': }]"},
+	},
+	{
+		lines: []string{
+			"func main() {",
+			"    debug{",
+			`        log("} is not a bracket")`,
+			"    }",
+			"    run()",
+			"}",
+		},
+		target: []string{
+			"func main() {",
+			"    debug",
+			"    run()",
+			"}",
+		},
+	},
+}
+
+type LevelBrackets struct {
+	cursor  Coord
+	history [][]string // buffers saved before each change, for undo
+	level   LevelID
+	lines   []string
+	message string
+	puzzle  int
+	typed   string
+}
+
+// deleteBufferRange deletes the text from one position to another, both included,
+// joining the first and last lines when the range spans lines.
+func deleteBufferRange(lines []string, from, to Coord) []string {
+	if to.y < from.y || (to.y == from.y && to.x < from.x) {
+		from, to = to, from
+	}
+	joined := lines[from.y][:from.x] + lines[to.y][to.x+1:]
+	result := make([]string, 0, len(lines)-(to.y-from.y))
+	result = append(result, lines[:from.y]...)
+	result = append(result, joined)
+	return append(result, lines[to.y+1:]...)
+}
+
+// isBracket returns true if ch is one of ()[]{}
+func isBracket(ch byte) bool {
+	_, ok := bracketPairs[ch]
+	return ok
+}
+
+// matchBracket finds the bracket matching the one at pos, as % does. If there is no bracket at pos
+// the first bracket after it on the line is used. Brackets inside quoted strings are ignored,
+// unless the starting bracket is itself quoted, when only that string is searched.
+func matchBracket(lines []string, pos Coord) (Coord, bool) {
+	if pos.y < 0 || pos.y >= len(lines) {
+		return pos, false
+	}
+
+	// find the bracket to start from
+	line := lines[pos.y]
+	quoted := quotedMask(line)
+	start := -1
+	for x := pos.x; x < len(line); x++ {
+		if isBracket(line[x]) && (x == pos.x || !quoted[x] || quoted[pos.x]) {
+			start = x
+			break
+		}
+	}
+	if start < 0 {
+		return pos, false
+	}
+
+	open := line[start]
+	closing := bracketPairs[open]
+	forward := open == '(' || open == '[' || open == '{'
+	inString := quoted[start]
+
+	depth := 0
+	y, x := pos.y, start
+	for {
+		line = lines[y]
+		quoted = quotedMask(line)
+		if x >= 0 && x < len(line) && quoted[x] == inString {
+			switch line[x] {
+			case open:
+				depth++
+			case closing:
+				depth--
+				if depth == 0 {
+					return Coord{x, y}, true
+				}
+			}
+		}
+
+		// step to the next position, a quoted string never continues onto another line
+		if forward {
+			x++
+		} else {
+			x--
+		}
+		if inString && (x < 0 || x >= len(line) || !quoted[x]) {
+			return pos, false
+		}
+		for x >= len(lines[y]) && forward {
+			if y == len(lines)-1 {
+				return pos, false
+			}
+			y++
+			x = 0
+		}
+		for x < 0 && !forward {
+			if y == 0 {
+				return pos, false
+			}
+			y--
+			x = len(lines[y]) - 1
+		}
+	}
+}
+
+// quotedMask marks the bytes of a line that are part of a quoted string, quotes included.
+// A ' between two letters is an apostrophe, as in don't, rather than a quote.
+func quotedMask(line string) []bool {
+	mask := make([]bool, len(line))
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			mask[i] = true
+			if ch == '\\' && i+1 < len(line) {
+				i++
+				mask[i] = true
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"':
+			quote = ch
+			mask[i] = true
+		case ch == '\'' && !(i > 0 && i+1 < len(line) && isLetter(line[i-1]) && isLetter(line[i+1])):
+			quote = ch
+			mask[i] = true
+		}
+	}
+	return mask
+}
+
+func (l *LevelBrackets) Draw(screen *ebiten.Image, now time.Duration) {
+	screen.Fill(darkCoal)
+	p := bracketPuzzles[l.puzzle]
+
+	drawBufferString(screen, fmt.Sprintf("Puzzle %d of %d", l.puzzle+1, len(bracketPuzzles)),
+		bracketsLeft, 20, 0, 0, bufferDimText)
+
+	// highlight the cursor and the bracket matching it
+	if match, ok := matchBracket(l.lines, l.cursor); ok {
+		drawBufferCell(screen, bracketsLeft, bracketsTop, match.x, match.y, colorBracketMatch)
+	}
+	cursorColors := [2]color.Color{redCursor, whiteCursor}
+	blink := blinkPhase(now, blinkInterval)
+	drawBufferCell(screen, bracketsLeft, bracketsTop, l.cursor.x, l.cursor.y, cursorColors[blink])
+
+	drawBufferLines(screen, l.lines, bracketsLeft, bracketsTop, true, bufferText)
+
+	// the target is drawn below the buffer
+	targetTop := bracketsTop + (len(p.lines)+2)*bufferLineHeight
+	drawBufferString(screen, "Target", bracketsLeft, targetTop, 0, -1, bufferDimText)
+	drawBufferLines(screen, p.target, bracketsLeft, targetTop, false, colorTargetText)
+
+	bottom := screenHeight - bufferLineHeight - 10
+	drawBufferString(screen, l.message, bracketsLeft, bottom, 0, 0, bufferText)
+	drawBufferString(screen, l.typed, screenWidth-120, bottom, 0, 0, bufferText)
+}
+
+func (l *LevelBrackets) Initialize(id LevelID) {
+	l.level = id
+	l.puzzle = 0
+	l.loadPuzzle()
+}
+
+func (l *LevelBrackets) Update(clock Clock) (bool, error) {
+	if isCheatKeyPressed() {
+		return true, nil
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		l.typed = ""
+	}
+	for _, ch := range globalChars {
+		l.typed += string(ch)
+		cmd, result := parseCommand(l.typed, bracketsCommands)
+		switch result {
+		case commandComplete:
+			l.execute(cmd)
+			l.typed = ""
+		case commandInvalid:
+			PlaySound(failOgg)
+			l.typed = ""
+		}
+	}
+	clearKeystrokes()
+
+	if equals(l.lines, bracketPuzzles[l.puzzle].target) {
+		if l.puzzle == len(bracketPuzzles)-1 {
+			return true, nil
+		}
+		PlaySound(tripleOgg)
+		l.puzzle++
+		l.loadPuzzle()
+	}
+	return false, nil
+}
+
+// clampCursor keeps the cursor on a character of the buffer
+func (l *LevelBrackets) clampCursor() {
+	l.cursor.y = limitToRange(l.cursor.y, 0, len(l.lines)-1)
+	l.cursor.x = limitToRange(l.cursor.x, 0, max(len(l.lines[l.cursor.y])-1, 0))
+}
+
+func (l *LevelBrackets) execute(cmd viCommand) {
+	l.message = ""
+	switch cmd.keys {
+	case "h":
+		l.cursor.x -= cmd.Count()
+	case "l":
+		l.cursor.x += cmd.Count()
+	case "k":
+		l.cursor.y -= cmd.Count()
+	case "j":
+		l.cursor.y += cmd.Count()
+	case "0":
+		l.cursor.x = 0
+	case "$":
+		l.cursor.x = len(l.lines[l.cursor.y]) - 1
+	case "f":
+		// find the next occurrence of a character on the line
+		line := l.lines[l.cursor.y]
+		found := strings.IndexRune(line[min(l.cursor.x+1, len(line)):], cmd.argument)
+		if found < 0 {
+			PlaySound(failOgg)
+			break
+		}
+		l.cursor.x += found + 1
+	case "%":
+		if match, ok := matchBracket(l.lines, l.cursor); ok {
+			l.cursor = match
+		} else {
+			PlaySound(failOgg)
+		}
+	case "d%":
+		match, ok := matchBracket(l.lines, l.cursor)
+		if !ok {
+			PlaySound(failOgg)
+			break
+		}
+		l.saveUndo()
+		l.lines = deleteBufferRange(l.lines, l.cursor, match)
+		if match.y < l.cursor.y || (match.y == l.cursor.y && match.x < l.cursor.x) {
+			l.cursor = match
+		}
+	case "u":
+		if len(l.history) == 0 {
+			l.message = "Already at oldest change"
+			PlaySound(failOgg)
+			break
+		}
+		l.lines = l.history[len(l.history)-1]
+		l.history = l.history[:len(l.history)-1]
+	}
+	l.clampCursor()
+}
+
+func (l *LevelBrackets) loadPuzzle() {
+	p := bracketPuzzles[l.puzzle]
+	l.lines = make([]string, len(p.lines))
+	copy(l.lines, p.lines)
+	l.cursor = Coord{0, 0}
+	l.history = nil
+	l.message = ""
+	l.typed = ""
+}
+
+func (l *LevelBrackets) saveUndo() {
+	saved := make([]string, len(l.lines))
+	copy(saved, l.lines)
+	l.history = append(l.history, saved)
+}
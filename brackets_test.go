@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchBracket(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		pos   Coord
+		want  Coord
+		ok    bool
+	}{
+		{"Parentheses forward", []string{"(a)"}, Coord{0, 0}, Coord{2, 0}, true},
+		{"Parentheses backward", []string{"(a)"}, Coord{2, 0}, Coord{0, 0}, true},
+		{"Square brackets", []string{"x[1]"}, Coord{1, 0}, Coord{3, 0}, true},
+		{"Braces", []string{"{}"}, Coord{0, 0}, Coord{1, 0}, true},
+		{"Empty pair backward", []string{"{}"}, Coord{1, 0}, Coord{0, 0}, true},
+		{"Nested outer", []string{"((()))"}, Coord{0, 0}, Coord{5, 0}, true},
+		{"Nested middle", []string{"((()))"}, Coord{1, 0}, Coord{4, 0}, true},
+		{"Nested inner", []string{"((()))"}, Coord{2, 0}, Coord{3, 0}, true},
+		{"Nested inner backward", []string{"((()))"}, Coord{3, 0}, Coord{2, 0}, true},
+		{"Siblings", []string{"()()"}, Coord{2, 0}, Coord{3, 0}, true},
+		{"Mixed types", []string{"([{}])"}, Coord{0, 0}, Coord{5, 0}, true},
+		{"Mixed types inner", []string{"([{}])"}, Coord{1, 0}, Coord{4, 0}, true},
+		{"Other types are not counted", []string{"(])"}, Coord{0, 0}, Coord{2, 0}, true},
+		{"Deep nesting", []string{"(((((((x)))))))"}, Coord{0, 0}, Coord{14, 0}, true},
+		{"Deep nesting backward", []string{"(((((((x)))))))"}, Coord{14, 0}, Coord{0, 0}, true},
+		{"Searches forward on the line", []string{"foo(bar)"}, Coord{0, 0}, Coord{7, 0}, true},
+		{"Searches forward from a closing bracket", []string{"a) (b)"}, Coord{0, 0}, Coord{0, 0}, false},
+		{"No bracket", []string{"abc"}, Coord{0, 0}, Coord{0, 0}, false},
+		{"Unmatched open", []string{"(("}, Coord{0, 0}, Coord{0, 0}, false},
+		{"Unmatched close", []string{"))"}, Coord{1, 0}, Coord{1, 0}, false},
+		{"Empty line", []string{""}, Coord{0, 0}, Coord{0, 0}, false},
+		{"Across lines", []string{"{", "x", "}"}, Coord{0, 0}, Coord{0, 2}, true},
+		{"Across lines backward", []string{"{", "x", "}"}, Coord{0, 2}, Coord{0, 0}, true},
+		{"Across empty lines", []string{"f() {", "", "  g()", "", "}"}, Coord{4, 0}, Coord{0, 4}, true},
+		{"Across empty lines backward", []string{"{", "", "", "}"}, Coord{0, 3}, Coord{0, 0}, true},
+		{"Nested across lines", []string{"{ {", "} {", "}", "}"}, Coord{0, 0}, Coord{0, 3}, true},
+		{"Nested across lines inner", []string{"{ {", "} {", "}", "}"}, Coord{2, 0}, Coord{0, 1}, true},
+		{"Skips brackets in double quotes", []string{`(")")`}, Coord{0, 0}, Coord{4, 0}, true},
+		{"Skips brackets in single quotes", []string{`f('(', x)`}, Coord{1, 0}, Coord{8, 0}, true},
+		{"Skips brackets in quotes backward", []string{`("(")`}, Coord{4, 0}, Coord{0, 0}, true},
+		{"Skips escaped quotes", []string{`("\")")`}, Coord{0, 0}, Coord{6, 0}, true},
+		{"Skips quoted brackets across lines", []string{"{", `"}"`, "}"}, Coord{0, 0}, Coord{0, 2}, true},
+		{"Apostrophe is not a quote", []string{"(don't)"}, Coord{0, 0}, Coord{6, 0}, true},
+		{"Brackets inside a string match each other", []string{`"(a)" (`}, Coord{1, 0}, Coord{3, 0}, true},
+		{"Quoted bracket does not match outside the string", []string{`"(" )`}, Coord{1, 0}, Coord{1, 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchBracket(tt.lines, tt.pos)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("matchBracket(%q, %v) = %v, %v; want %v, %v", tt.lines, tt.pos, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestQuotedMask(t *testing.T) {
+	tests := []struct {
+		line string
+		want string // q for quoted bytes
+	}{
+		{`a"b"c`, ".qqq."},
+		{`'x' y`, "qqq.."},
+		{`don't`, "....."},
+		{`"a\"b"`, "qqqqqq"},
+		{`"open`, "qqqqq"},
+	}
+
+	for _, tt := range tests {
+		mask := quotedMask(tt.line)
+		got := make([]byte, len(mask))
+		for i, q := range mask {
+			got[i] = '.'
+			if q {
+				got[i] = 'q'
+			}
+		}
+		if string(got) != tt.want {
+			t.Errorf("quotedMask(%q) = %s; want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteBufferRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		from, to Coord
+		want     []string
+	}{
+		{"Within a line", []string{"print(debug(x))"}, Coord{6, 0}, Coord{13, 0}, []string{"print()"}},
+		{"Reversed", []string{"a(b)c"}, Coord{3, 0}, Coord{1, 0}, []string{"ac"}},
+		{"Across lines", []string{"x {", "y", "} z", "w"}, Coord{2, 0}, Coord{0, 2}, []string{"x  z", "w"}},
+		{"Whole buffer", []string{"{", "}"}, Coord{0, 0}, Coord{0, 1}, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deleteBufferRange(tt.lines, tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deleteBufferRange() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBracketPuzzlesAreSolvable(t *testing.T) {
+	// the cursor positions to d% from to solve each puzzle
+	solutions := [][]Coord{
+		{{6, 0}},
+		{{9, 0}},
+		{{8, 0}, {16, 0}},
+		{{9, 1}},
+	}
+	if len(solutions) != len(bracketPuzzles) {
+		t.Fatalf("%d solutions for %d puzzles", len(solutions), len(bracketPuzzles))
+	}
+
+	for i, p := range bracketPuzzles {
+		lines := append([]string{}, p.lines...)
+		for _, pos := range solutions[i] {
+			match, ok := matchBracket(lines, pos)
+			if !ok {
+				t.Fatalf("puzzle %d: no match from %v in %q", i, pos, lines)
+			}
+			lines = deleteBufferRange(lines, pos, match)
+		}
+		if !reflect.DeepEqual(lines, p.target) {
+			t.Errorf("puzzle %d: got %q; want %q", i, lines, p.target)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+)
+
+/*
+ * Brick layouts are drawn as ASCII maps in files under assets/levels, one character for each
+ * brick:
+ *
+ *	. or space  no brick
+ *	1, 2, 3     a brick taking that many hits to break
+ *	#           an indestructible brick
+ *	*           a power-up brick, broken by one hit
+ */
+
+// BrickKind is the type of a brick
+type BrickKind int
+
+const (
+	BrickNone BrickKind = iota
+	BrickNormal
+	BrickSolid // indestructible
+	BrickPowerUp
+)
+
+// Brick is one square of a brick layout
+type Brick struct {
+	kind BrickKind
+	hits int // hits left to break the brick
+}
+
+// brickLayouts are the maps of the bricks levels
+var brickLayouts = map[LevelID]string{
+	LevelIdBricksHL:   "assets/levels/bricks_hl.txt",
+	LevelIdBricksHJKL: "assets/levels/bricks_hjkl.txt",
+}
+
+// Present returns true if there is a brick in the square
+func (b Brick) Present() bool {
+	return b.kind != BrickNone
+}
+
+// Breakable returns true if the brick is one that must be broken to win
+func (b Brick) Breakable() bool {
+	return b.kind == BrickNormal || b.kind == BrickPowerUp
+}
+
+// Hit takes a hit point from a breakable brick, removing it when it has none left.
+// It returns true if the brick broke.
+func (b *Brick) Hit() bool {
+	if !b.Breakable() {
+		return false
+	}
+	b.hits--
+	if b.hits > 0 {
+		return false
+	}
+	*b = Brick{}
+	return true
+}
+
+// Color returns the color of the brick, darker the more hits it takes to break
+func (b Brick) Color() color.Color {
+	switch {
+	case b.kind == BrickSolid:
+		return mediumAluminium
+	case b.kind == BrickPowerUp:
+		return mediumButter
+	case b.hits >= 3:
+		return darkScarletRed
+	case b.hits == 2:
+		return mediumScarletRed
+	}
+	return brightRed
+}
+
+// parseBrickMap reads a brick layout, one row of bricks on each line. Short lines are padded
+// with empty squares and blank lines at the end are ignored.
+func parseBrickMap(text string) (Grid[Brick], error) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	columns := 0
+	for _, line := range lines {
+		columns = max(columns, len(strings.TrimRight(line, "\r")))
+	}
+	if columns == 0 {
+		return Grid[Brick]{}, fmt.Errorf("brick map has no bricks")
+	}
+	bricks := NewGrid[Brick](columns, len(lines))
+	for y, line := range lines {
+		for x, ch := range strings.TrimRight(line, "\r") {
+			var b Brick
+			switch ch {
+			case '.', ' ':
+			case '1', '2', '3':
+				b = Brick{kind: BrickNormal, hits: int(ch - '0')}
+			case '#':
+				b = Brick{kind: BrickSolid}
+			case '*':
+				b = Brick{kind: BrickPowerUp, hits: 1}
+			default:
+				return Grid[Brick]{}, fmt.Errorf("line %d: unknown brick %q", y+1, ch)
+			}
+			bricks.Set(Coord{x, y}, b)
+		}
+	}
+	return bricks, nil
+}
+
+// loadBrickMap loads the brick layout of a level from the embedded assets
+func loadBrickMap(id LevelID) Grid[Brick] {
+	data, err := embeddedAssets.ReadFile(brickLayouts[id])
+	if err != nil {
+		log.Fatalf("Error loading brick map: %v", err)
+	}
+	bricks, err := parseBrickMap(string(data))
+	if err != nil {
+		log.Fatalf("Error in brick map %s: %v", brickLayouts[id], err)
+	}
+	return bricks
+}
+
+// anyBreakable returns true if any brick still has to be broken
+func anyBreakable(bricks Grid[Brick]) bool {
+	found := false
+	bricks.ForEach(func(p Coord, b Brick) {
+		found = found || b.Breakable()
+	})
+	return found
+}
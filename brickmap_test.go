@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseBrickMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    [][]Brick
+		wantErr bool
+	}{
+		{"Brick types", "1.2\n3#*\n", [][]Brick{
+			{{BrickNormal, 1}, {}, {BrickNormal, 2}},
+			{{BrickNormal, 3}, {BrickSolid, 0}, {BrickPowerUp, 1}},
+		}, false},
+		{"Short lines are padded", "11\n1\n", [][]Brick{
+			{{BrickNormal, 1}, {BrickNormal, 1}},
+			{{BrickNormal, 1}, {}},
+		}, false},
+		{"Windows line endings", "1 1\r\n", [][]Brick{
+			{{BrickNormal, 1}, {}, {BrickNormal, 1}},
+		}, false},
+		{"Unknown brick", "1x1\n", nil, true},
+		{"Empty", "\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBrickMap(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBrickMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseBrickMap() has %d rows, want %d", len(got), len(tt.want))
+			}
+			for y := range tt.want {
+				if !equals(got[y], tt.want[y]) {
+					t.Errorf("parseBrickMap() row %d = %v, want %v", y, got[y], tt.want[y])
+				}
+			}
+		})
+	}
+}
+
+func TestBrickHit(t *testing.T) {
+	tests := []struct {
+		name       string
+		brick      Brick
+		wantBroken bool
+		want       Brick
+	}{
+		{"One hit", Brick{BrickNormal, 1}, true, Brick{}},
+		{"Two hits", Brick{BrickNormal, 2}, false, Brick{BrickNormal, 1}},
+		{"Indestructible", Brick{BrickSolid, 0}, false, Brick{BrickSolid, 0}},
+		{"Power-up", Brick{BrickPowerUp, 1}, true, Brick{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.brick
+			if broken := b.Hit(); broken != tt.wantBroken || b != tt.want {
+				t.Errorf("Hit() = %v leaving %v, want %v leaving %v", broken, b, tt.wantBroken, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyBreakable(t *testing.T) {
+	solid := [][]Brick{{{BrickSolid, 0}, {}}}
+	if anyBreakable(solid) {
+		t.Errorf("anyBreakable() = true for only indestructible bricks")
+	}
+	if !anyBreakable([][]Brick{{{BrickSolid, 0}, {BrickNormal, 2}}}) {
+		t.Errorf("anyBreakable() = false with a brick left to break")
+	}
+}
+
+func TestBrickLayoutsParse(t *testing.T) {
+	for id, path := range brickLayouts {
+		data, err := embeddedAssets.ReadFile(path)
+		if err != nil {
+			t.Fatalf("level %d: %v", id, err)
+		}
+		bricks, err := parseBrickMap(string(data))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if !anyBreakable(bricks) {
+			t.Errorf("%s: no bricks to break", path)
+		}
+	}
+}
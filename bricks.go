@@ -1,8 +1,8 @@
 package main
 
 import (
-	"log"
-	"math"
+	"image/color"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
@@ -16,304 +16,430 @@ const (
 	paddlesXHeight = 20
 	paddlesYWidth  = paddlesXHeight
 	paddlesYHeight = paddlesXWidth
-	paddleSpeed    = 5
+	paddleSpeed    = 5 // pixels a tick
+
+	brickPoints      = 10 // for each hit on a breakable brick
+	minimumBallSpeed = 3.0
+	maximumBallSpeed = 8.0
+	ballSpeedUp      = 0.25                   // added to the minimum speed of the ball in the four paddle level
+	speedUpInterval  = 5 * time.Second        // between each speed up
+	vanishTime       = 330 * time.Millisecond // a broken brick takes to vanish
 )
 
+// VanishingBrick is a broken brick, drawn as it vanishes
+type VanishingBrick struct {
+	color         color.Color
+	width, height float64
+	transform     Transform
+	tween         Tweener
+}
+
+// Ball is a ball in play, moving by dx, dy each tick
+type Ball struct {
+	x, y   float32
+	dx, dy float32
+}
+
 type LevelBricksHL struct {
-	ballDX       float32
-	ballDY       float32
-	ballX        float32
-	ballY        float32
-	bricks       [][]bool
+	balls        []Ball
+	bricks       Grid[Brick]
 	brickHeight  int
 	brickWidth   int
 	brickLeft    int
 	brickTop     int
 	level        LevelID
+	lost         []Ball // balls that left the screen, last lost last, for u to restore
 	minimumSpeed float64
-	numBrickRows int
-	numBrickCols int
+	particles    ParticleSystem
 	paddlesX     float32
 	paddlesY     float32
+	paddleWidth  float32       // width of the horizontal paddles, wider after w
+	rallyTime    time.Duration // the served ball has been in play
+	restoreBy    time.Duration // game time the last ball lost must be restored by, before a life is lost
+	scoreboard   Scoreboard
+	drops        []PowerUpDrop
+	held         []PowerUpKind // power-ups caught and waiting for their command
+	typed        string        // characters of a partly typed power-up command
+	vanishing    []VanishingBrick
+	whitePixel   *ebiten.Image // scaled and colored to draw vanishing bricks
+	widenedUntil time.Duration // game time the paddle goes back to its normal width
 }
 
-// return true is any value in the 2D slice is true
-func anyIn2DSlice(bools [][]bool) bool {
-	for _, row := range bools {
-		if b := anyInSlice(row); b {
-			return true
-		}
-	}
-	return false
+// collider is something the ball bounces off
+type collider struct {
+	rect  Rect
+	kind  colliderKind
+	brick Coord // the brick hit, for a brick
 }
 
-// return true is any value in the slice is true
-func anyInSlice(bools []bool) bool {
-	for _, b := range bools {
-		if b {
-			return true
+type colliderKind int
+
+const (
+	colliderWall colliderKind = iota
+	colliderBrick
+	colliderPaddleBottom
+	colliderPaddleTop
+	colliderPaddleLeft
+	colliderPaddleRight
+)
+
+// maxBounces limits the hits in one step, so a ball wedged between a paddle and a wall stops for a frame
+// rather than bouncing forever
+const maxBounces = 8
+
+// colliders returns the walls, bricks and paddles the ball can hit
+func (l *LevelBricksHL) colliders() []collider {
+	var cs []collider
+	if l.level == LevelIdBricksHL {
+		// the walls are outside the screen so the ball bounces off the edges
+		cs = append(cs,
+			collider{rect: Rect{-screenWidth, -screenHeight, screenWidth, 3 * screenHeight}, kind: colliderWall},
+			collider{rect: Rect{screenWidth, -screenHeight, screenWidth, 3 * screenHeight}, kind: colliderWall},
+			collider{rect: Rect{-screenWidth, -screenHeight, 3 * screenWidth, screenHeight}, kind: colliderWall})
+	}
+	l.bricks.ForEach(func(p Coord, brick Brick) {
+		if brick.Present() {
+			cs = append(cs, collider{rect: l.brickRect(p.x, p.y), kind: colliderBrick, brick: p})
 		}
+	})
+	cs = append(cs, collider{rect: Rect{l.paddlesX, screenHeight - paddlesXHeight, l.paddleWidth, paddlesXHeight},
+		kind: colliderPaddleBottom})
+	if l.level == LevelIdBricksHJKL {
+		cs = append(cs,
+			collider{rect: Rect{l.paddlesX, 0, l.paddleWidth, paddlesXHeight}, kind: colliderPaddleTop},
+			collider{rect: Rect{0, l.paddlesY, paddlesYWidth, paddlesYHeight}, kind: colliderPaddleLeft},
+			collider{rect: Rect{screenWidth - paddlesYWidth, l.paddlesY, paddlesYWidth, paddlesYHeight},
+				kind: colliderPaddleRight})
 	}
-	return false
+	return cs
 }
 
-func isPointInRect(pointX, pointY, rectLeft, rectTop, rectWidth, rectHeight float32) bool {
-	if pointX > rectLeft && pointX < rectLeft+rectWidth &&
-		pointY > rectTop && pointY < rectTop+rectHeight {
-		return true
-	}
-	return false
+func (l *LevelBricksHL) brickRect(x, y int) Rect {
+	return Rect{float32(x*l.brickWidth + l.brickLeft), float32(y*l.brickHeight + l.brickTop),
+		float32(l.brickWidth), float32(l.brickHeight)}
 }
 
-func (l *LevelBricksHL) CheckBrickCollisions() {
-	// Check for brick collision
-	for y, row := range l.bricks {
-		for x, brick := range row {
-			if brick {
-				if isCircleTouchingRect(l.ballX, l.ballY, ballRadius, float32(x*l.brickWidth+l.brickLeft),
-					float32(y*l.brickHeight+l.brickTop), float32(l.brickWidth), float32(l.brickHeight)) {
-
-					// we have a collision,clear the brick
-					l.bricks[y][x] = false
-
-					//let's find the closest edge to determine which direction to change
-					leftD := math.Abs(float64(l.ballX - float32(x*l.brickWidth+l.brickLeft)))
-					rightD := math.Abs(float64(l.ballX - float32((x+1)*l.brickWidth+l.brickLeft)))
-					topD := math.Abs(float64(l.ballY - float32(y*l.brickHeight+l.brickTop)))
-					bottomD := math.Abs(float64(l.ballY - float32((y+1)*l.brickHeight+l.brickTop)))
-					minD := math.Min(leftD, rightD)
-					minD = math.Min(minD, topD)
-					minD = math.Min(minD, bottomD)
-					switch {
-					case minD == leftD:
-						l.ballDX *= -1
-					case minD == rightD:
-						l.ballDX *= -1
-					case minD == topD:
-						l.ballDY *= -1
-					case minD == bottomD:
-						l.ballDY *= -1
-					default:
-						log.Fatal("Bad collision calculation")
-					}
-
-					PlaySound(brickOgg)
-				}
-			}
+// firstCollision returns the collider the ball hits first when moving by dx, dy
+func (l *LevelBricksHL) firstCollision(b *Ball, dx, dy float32) (collider, Collision, bool) {
+	var first collider
+	var hit Collision
+	found := false
+	for _, c := range l.colliders() {
+		if h, ok := sweepCircle(b.x, b.y, dx, dy, ballRadius, c.rect); ok && (!found || h.t < hit.t) {
+			first, hit, found = c, h, true
 		}
 	}
+	return first, hit, found
 }
 
-func (l *LevelBricksHL) CheckPaddleCollisions() {
-	// Check for bottom paddle collision
-	if l.ballY+ballRadius > screenHeight-paddlesXHeight &&
-		l.ballX >= l.paddlesX && l.ballX <= l.paddlesX+paddlesXWidth {
-		l.ballDY *= -1
-
-		// modify angle depending on where the ball hits the paddle
-		ratio := (l.ballX - l.paddlesX) / paddlesXWidth
-		l.ballDX = (ratio*4 - 2) * 1.5
-		//log.Printf("ratio %f, dx is %f, dy is %f", ratio, l.ballDX, l.ballDY)
-
-		PlaySound(paddleOgg)
+// moveBall moves the ball on by a step of the clock, bouncing off everything it hits on the way.
+// After a bounce the rest of the step is made in the new direction.
+func (l *LevelBricksHL) moveBall(b *Ball, now, step time.Duration) {
+	remaining := float32(ticks(step))
+	for range maxBounces {
+		dx, dy := b.dx*remaining, b.dy*remaining
+		c, hit, ok := l.firstCollision(b, dx, dy)
+		if !ok {
+			b.x += dx
+			b.y += dy
+			return
+		}
+		b.x += dx * hit.t
+		b.y += dy * hit.t
+		b.dx, b.dy = bounce(b.dx, b.dy, hit)
+		l.collide(b, c, hit, now)
+		remaining *= 1 - hit.t
 	}
+}
 
-	if l.level == LevelIdBricksHJKL {
-		// check top paddle collision
-		if l.ballY-ballRadius < paddlesXHeight &&
-			l.ballX >= l.paddlesX && l.ballX <= l.paddlesX+paddlesXWidth {
-			l.ballDY *= -1
-
-			// modify angle depending on where the ball hits the paddle
-			ratio := (l.ballX - l.paddlesX) / paddlesXWidth
-			l.ballDX = ratio*4 - 2
-			//log.Printf("ratio %f, dx is %f, dy is %f", ratio, l.ballDX, l.ballDY)
-
+// collide takes a hit point from a brick that was hit, a power-up brick drops a power-up when it
+// breaks. A paddle hit on its face sends the ball off at an angle depending on where it hits the paddle.
+func (l *LevelBricksHL) collide(b *Ball, c collider, hit Collision, now time.Duration) {
+	switch c.kind {
+	case colliderBrick:
+		brick := l.bricks.GetPtr(c.brick)
+		if brick.Breakable() {
+			kind, clr := brick.kind, brick.Color()
+			r := l.brickRect(c.brick.x, c.brick.y)
+			if brick.Hit() {
+				l.vanishing = append(l.vanishing, VanishingBrick{
+					color:     clr,
+					width:     float64(r.width),
+					height:    float64(r.height),
+					transform: NewTransform(float64(r.left), float64(r.top)),
+					tween:     VanishTween(vanishTime),
+				})
+				l.particles.Emit("brick", float64(r.left+r.width/2), float64(r.top+r.height/2), clr)
+				if kind == BrickPowerUp {
+					l.dropPowerUp(r.left+r.width/2, r.top+r.height/2)
+				}
+			}
+			l.scoreboard.Add(brickPoints, r.left+r.width/2, r.top, now)
+			PlaySound(brickOgg)
+		} else {
 			PlaySound(paddleOgg)
 		}
-
-		// check left paddle
-		if l.ballX-ballRadius < paddlesYWidth &&
-			l.ballY >= l.paddlesY && l.ballY <= l.paddlesY+paddlesYHeight {
-			l.ballDX *= -1
-
-			// modify angle depending on where the ball hits the paddle
-			ratio := (l.ballY - l.paddlesY) / paddlesYHeight
-			l.ballDY = ratio*4 - 2
-			//log.Printf("ratio %f, dx is %f, dy is %f", ratio, l.ballDX, l.ballDY)
-
-			PlaySound(paddleOgg)
+		return
+	case colliderWall:
+		return
+	case colliderPaddleBottom:
+		if hit.ny < 0 {
+			ratio := (b.x - l.paddlesX) / l.paddleWidth
+			b.dx = paddleDeflection(ratio)
 		}
-
-		// check right paddle
-		if l.ballX+ballRadius > screenWidth-paddlesYWidth &&
-			l.ballY >= l.paddlesY && l.ballY <= l.paddlesY+paddlesYHeight {
-			l.ballDX *= -1
-
-			// modify angle depending on where the ball hits the paddle
-			ratio := (l.ballY - l.paddlesY) / paddlesYHeight
-			l.ballDY = ratio*4 - 2
-			//log.Printf("ratio %f, dx is %f, dy is %f", ratio, l.ballDX, l.ballDY)
-
-			PlaySound(paddleOgg)
+	case colliderPaddleTop:
+		if hit.ny > 0 {
+			ratio := (b.x - l.paddlesX) / l.paddleWidth
+			b.dx = paddleDeflection(ratio)
+		}
+	case colliderPaddleLeft:
+		if hit.nx > 0 {
+			ratio := (b.y - l.paddlesY) / paddlesYHeight
+			b.dy = paddleDeflection(ratio)
+		}
+	case colliderPaddleRight:
+		if hit.nx < 0 {
+			ratio := (b.y - l.paddlesY) / paddlesYHeight
+			b.dy = paddleDeflection(ratio)
 		}
 	}
+	// ensure the ball speed is not too slow
+	b.dx, b.dy = enforceMinimumSpeed(b.dx, b.dy, l.minimumSpeed)
+	PlaySound(paddleOgg)
+}
+
+// paddleDeflection returns the speed across a paddle that a ball is sent off at, from -3 at one
+// end of the paddle to 3 at the other. ratio is where the ball hits the paddle, 0 to 1.
+func paddleDeflection(ratio float32) float32 {
+	return (ratio*4 - 2) * 1.5
+}
 
-	if l.ballDX != 0.0 {
-		// ensure the ball speed is not too slow
-		for math.Abs(float64(l.ballDX))+math.Abs(float64(l.ballDY)) < l.minimumSpeed {
-			l.ballDX *= 1.1
-			l.ballDY *= 1.1
+// checkBallLost removes the balls that have left the screen past a paddle. When every ball is
+// lost a life is lost and a new ball is served, unless a lost ball can still be restored with u.
+// Then the player has restoreWait to type it.
+func (l *LevelBricksHL) checkBallLost(now time.Duration) {
+	balls := l.balls[:0]
+	for _, b := range l.balls {
+		if l.isLost(b) {
+			l.lost = append(l.lost, b)
+		} else {
+			balls = append(balls, b)
 		}
 	}
+	l.balls = balls
+	switch {
+	case len(l.balls) > 0:
+		l.restoreBy = 0
+		return
+	case l.holds(PowerUpRestore) && l.restoreBy == 0:
+		l.restoreBy = now + restoreWait
+		return
+	case l.holds(PowerUpRestore) && now < l.restoreBy:
+		return
+	}
+	l.restoreBy = 0
+	// balls lost in this life can't be restored in the next
+	l.lost = nil
+	if l.scoreboard.LoseLife() {
+		l.serveBall()
+	}
+	PlaySound(failOgg)
 }
 
-func (l *LevelBricksHL) CheckWallCollisions() {
-	// Check for wall collisions
-	if l.level == LevelIdBricksHL {
-		if l.ballX < 0 || l.ballX > screenWidth-ballRadius {
-			l.ballDX *= -1
-		}
-		if l.ballY < 0 {
-			l.ballDY *= -1
-		}
+// serveBall puts a still ball in its starting place, above the brick cluster in the four
+// paddle level. It starts moving when a paddle is first moved.
+func (l *LevelBricksHL) serveBall() {
+	switch l.level {
+	case LevelIdBricksHL:
+		l.balls = []Ball{{x: screenWidth / 2, y: screenHeight / 3 * 2}}
+	case LevelIdBricksHJKL:
+		l.balls = []Ball{{
+			x: float32(l.brickLeft + l.brickWidth*l.bricks.NumColumns()/2),
+			y: float32(l.brickTop) - (ballRadius*2 + 1),
+		}}
 	}
+	l.minimumSpeed = minimumBallSpeed
+	l.rallyTime = 0
+}
 
-	// Check for ball off bottom of screen
-	if l.ballY+ballRadius > screenHeight {
-		l.Initialize(l.level)
+// speedUp makes the balls in the four paddle level faster the longer a rally lasts
+func (l *LevelBricksHL) speedUp(step time.Duration) {
+	if l.level != LevelIdBricksHJKL || len(l.balls) == 0 || (l.balls[0].dx == 0 && l.balls[0].dy == 0) {
+		return
+	}
+	l.rallyTime += step
+	l.minimumSpeed = min(minimumBallSpeed+ballSpeedUp*float64(l.rallyTime/speedUpInterval), maximumBallSpeed)
+	for i := range l.balls {
+		b := &l.balls[i]
+		b.dx, b.dy = enforceMinimumSpeed(b.dx, b.dy, l.minimumSpeed)
 	}
+}
 
+// isLost returns true if the ball has gone past the edge of the screen, the bottom edge or in
+// the four paddle level any edge
+func (l *LevelBricksHL) isLost(b Ball) bool {
+	if b.y-ballRadius > screenHeight {
+		return true
+	}
 	if l.level == LevelIdBricksHJKL {
-		// Check for ball off top of screen
-		if l.ballY+ballRadius < 0 {
-			l.Initialize(l.level)
-		}
-		// Check for ball off left of screen
-		if l.ballX+ballRadius < 0 {
-			l.Initialize(l.level)
-		}
-		// Check for ball off right of screen
-		if l.ballX+ballRadius > screenWidth {
-			l.Initialize(l.level)
-		}
+		return b.y+ballRadius < 0 || b.x+ballRadius < 0 || b.x-ballRadius > screenWidth
 	}
+	return false
 }
 
-func (l *LevelBricksHL) Draw(screen *ebiten.Image, frameCount int) {
+func (l *LevelBricksHL) Draw(screen *ebiten.Image, now time.Duration) {
 	// Draw background
 	screen.Fill(darkCoal)
 
 	// Draw paddle
-	vector.DrawFilledRect(screen, l.paddlesX, screenHeight-paddlesXHeight, paddlesXWidth, paddlesXHeight, darkAluminium, false)
+	vector.DrawFilledRect(screen, l.paddlesX, screenHeight-paddlesXHeight, l.paddleWidth, paddlesXHeight, darkAluminium, false)
 	if l.level == LevelIdBricksHJKL {
-		vector.DrawFilledRect(screen, l.paddlesX, 0, paddlesXWidth, paddlesXHeight, darkAluminium, false)
+		vector.DrawFilledRect(screen, l.paddlesX, 0, l.paddleWidth, paddlesXHeight, darkAluminium, false)
 		vector.DrawFilledRect(screen, 0, l.paddlesY, paddlesYWidth, paddlesYHeight, darkAluminium, false)
 		vector.DrawFilledRect(screen, screenWidth-paddlesYWidth, l.paddlesY, paddlesYWidth, paddlesYHeight, darkAluminium, false)
 	}
-	// Draw ball
-	vector.DrawFilledCircle(screen, l.ballX, l.ballY, ballRadius, darkAluminium, false)
+	// Draw balls
+	for _, b := range l.balls {
+		vector.DrawFilledCircle(screen, b.x, b.y, ballRadius, darkAluminium, false)
+	}
 
 	// Draw bricks with borders
-	for y := 0; y < len(l.bricks); y++ {
-		for x := 0; x < len(l.bricks[y]); x++ {
-			if l.bricks[y][x] {
-				// Draw brick
-				vector.DrawFilledRect(screen, float32(x*l.brickWidth+l.brickLeft), float32(y*l.brickHeight+l.brickTop),
-					float32(l.brickWidth), float32(l.brickHeight), brightRed, false)
-				// Draw border
-				vector.StrokeRect(screen, float32(x*l.brickWidth+l.brickLeft), float32(y*l.brickHeight+l.brickTop),
-					float32(l.brickWidth), float32(l.brickHeight), outlineWidth, mediumCoal, false)
-			}
+	l.bricks.ForEach(func(p Coord, brick Brick) {
+		if brick.Present() {
+			r := l.brickRect(p.x, p.y)
+			// Draw brick
+			vector.DrawFilledRect(screen, r.left, r.top, r.width, r.height, brick.Color(), false)
+			// Draw border
+			vector.StrokeRect(screen, r.left, r.top, r.width, r.height, outlineWidth, mediumCoal, false)
 		}
+	})
+	for _, v := range l.vanishing {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(v.width, v.height)
+		op.ColorScale.ScaleWithColor(v.color)
+		v.transform.Apply(op, v.width, v.height)
+		screen.DrawImage(l.whitePixel, op)
 	}
+	l.particles.Draw(screen)
+	l.drawPowerUps(screen, now)
+	l.scoreboard.Draw(screen, now, bufferText)
 }
 
 func (l *LevelBricksHL) Initialize(id LevelID) {
 	l.level = id
+	l.bricks = loadBrickMap(id)
+	l.vanishing = nil
+	l.particles.Clear()
+	if l.whitePixel == nil {
+		l.whitePixel = ebiten.NewImage(1, 1)
+		l.whitePixel.Fill(color.White)
+	}
 	switch id {
 	case LevelIdBricksHL:
-		l.numBrickRows = 3
-		l.numBrickCols = 5
-		l.brickWidth = screenWidth / l.numBrickCols
-		l.brickHeight = 50
-		l.brickWidth = screenWidth / l.numBrickCols
-		l.brickHeight = 50
+		l.brickWidth = screenWidth / l.bricks.NumColumns()
+		l.brickHeight = 30
 
 		l.paddlesX = screenWidth/2 - paddlesXWidth/2
-		l.ballX = screenWidth / 2
-		l.ballY = screenHeight / 3 * 2
 	case LevelIdBricksHJKL:
 		l.brickWidth = 50
 		l.brickHeight = 50
-		l.numBrickRows = 3
-		l.numBrickCols = 5
-		l.brickLeft = (screenWidth - l.brickWidth*l.numBrickCols) / 2
-		l.brickTop = (screenHeight - l.brickHeight*l.numBrickRows) / 2
+		l.brickLeft = (screenWidth - l.brickWidth*l.bricks.NumColumns()) / 2
+		l.brickTop = (screenHeight - l.brickHeight*l.bricks.NumRows()) / 2
 
 		l.paddlesX = screenWidth/2 - paddlesXWidth/2
 		l.paddlesY = screenHeight/2 - paddlesYHeight/2
-		l.ballY = float32(l.brickTop) - (ballRadius*2 + 1)
-		l.ballX = float32(l.brickLeft + (l.brickHeight * (l.numBrickRows / 2)))
 	}
 
-	l.ballDX = 0
-	l.ballDY = 0
-	l.minimumSpeed = 3.0
-	l.bricks = make([][]bool, l.numBrickRows)
-	for y := range l.bricks {
-		l.bricks[y] = make([]bool, l.numBrickCols)
-		fillSlice(l.bricks[y], true)
-	}
+	l.serveBall()
+	l.scoreboard = NewScoreboard(id)
+	l.paddleWidth = paddlesXWidth
+	l.widenedUntil = 0
+	l.restoreBy = 0
+	l.lost = nil
+	l.drops = nil
+	l.held = nil
+	l.typed = ""
 }
 
-func (l *LevelBricksHL) Update(frameCount int) (bool, error) {
+func (l *LevelBricksHL) Update(clock Clock) (bool, error) {
 	if isCheatKeyPressed() {
 		return true, nil
 	}
-	l.UpdateBallPosition()
-	l.UpdatePaddlePositions()
-	l.CheckWallCollisions()
-	l.CheckBrickCollisions()
-	l.CheckPaddleCollisions()
-
-	// check for end of level
-	if !anyIn2DSlice(l.bricks) {
+	if l.scoreboard.GameOver() {
+		return false, nil
+	}
+	// everything moves on by the step of the clock
+	now, step := clock.Now(), clock.Step()
+	l.UpdatePaddlePositions(step)
+	l.handlePowerUpCommands(now)
+	l.speedUp(step)
+	l.UpdateBallPosition(now, step)
+	l.updateDrops(now, step)
+	l.updateVanishing(step)
+	l.particles.Update(step)
+	l.checkBallLost(now)
+	l.scoreboard.Update(now)
+
+	// check for end of level, indestructible bricks are left standing
+	if !anyBreakable(l.bricks) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// updateVanishing advances the broken bricks as they vanish
+func (l *LevelBricksHL) updateVanishing(step time.Duration) {
+	vanishing := l.vanishing[:0]
+	for _, v := range l.vanishing {
+		if !v.tween.Step(&v.transform, step) {
+			vanishing = append(vanishing, v)
+		}
+	}
+	l.vanishing = vanishing
+}
+
 func (l *LevelBricksHL) initBallMovement() {
-	if l.ballDX == 0 {
+	if len(l.balls) == 1 && l.balls[0].dx == 0 && l.balls[0].dy == 0 {
 		if l.level == LevelIdBricksHL {
-			l.ballDX = 0.1
-			l.ballDY = -ballSpeedY
+			l.balls[0].dx = 0.1
+			l.balls[0].dy = -ballSpeedY
 		} else {
-			l.ballDX = -2.0
-			l.ballDY = 0.1
+			l.balls[0].dx = -2.0
+			l.balls[0].dy = 0.1
 
 		}
 	}
 }
 
-func (l *LevelBricksHL) UpdateBallPosition() {
-	if anyIn2DSlice(l.bricks) {
-		l.ballX += l.ballDX
-		l.ballY += l.ballDY
+func (l *LevelBricksHL) UpdateBallPosition(now, step time.Duration) {
+	if anyBreakable(l.bricks) {
+		for i := range l.balls {
+			l.moveBall(&l.balls[i], now, step)
+		}
 	}
 }
 
-func (l *LevelBricksHL) UpdatePaddlePositions() {
+// Failed returns true when the last life is lost
+func (l *LevelBricksHL) Failed() (string, bool) {
+	return l.scoreboard.Failed()
+}
+
+// Score returns the points for the bricks hit
+func (l *LevelBricksHL) Score() int {
+	return l.scoreboard.score
+}
+
+func (l *LevelBricksHL) UpdatePaddlePositions(step time.Duration) {
+	move := paddleSpeed * float32(ticks(step))
 	// Update paddle horizontal position based on keyboard input
 	heldLeft := ebiten.IsKeyPressed(ebiten.KeyH)
 	heldRight := ebiten.IsKeyPressed(ebiten.KeyL)
 	if heldLeft || heldRight {
 		if heldLeft && !heldRight {
-			l.paddlesX -= paddleSpeed
+			l.paddlesX -= move
 		} else if !heldLeft && heldRight {
-			l.paddlesX += paddleSpeed
+			l.paddlesX += move
 		}
 
 		// the level waits for the first paddle move before starting the ball
@@ -326,16 +452,16 @@ func (l *LevelBricksHL) UpdatePaddlePositions() {
 		heldUp := ebiten.IsKeyPressed(ebiten.KeyK)
 		if heldDown || heldUp {
 			if heldDown && !heldUp {
-				l.paddlesY += paddleSpeed
+				l.paddlesY += move
 			} else if !heldDown && heldUp {
-				l.paddlesY -= paddleSpeed
+				l.paddlesY -= move
 			}
 			l.initBallMovement()
 		}
 	}
 	// limit paddle movement within screen bounds,
 	// allow to move off screen by 1/2 paddle width
-	l.paddlesX = limitToRange(l.paddlesX, 0-paddlesXWidth/2, screenWidth-paddlesXWidth/2)
+	l.paddlesX = limitToRange(l.paddlesX, 0-l.paddleWidth/2, screenWidth-l.paddleWidth/2)
 	if l.level == LevelIdBricksHJKL {
 		l.paddlesY = limitToRange(l.paddlesY, 0, screenHeight-paddlesYHeight)
 	}
@@ -0,0 +1,240 @@
+package main
+
+import "slices"
+
+/*
+ * Matches and cascades on a Board. A match is a run of three or more identical gems in a row
+ * or column, together with any runs crossing it, so a run of 4 or 5 and an L or T of gems are
+ * each a single match. Resolving the board clears the matches, collapses and refills it, and
+ * repeats while the refill makes new matches, each step of the chain scoring more than the last.
+ */
+
+// MatchShape is the shape of the gems in a match, longer runs and crossing runs score more
+type MatchShape int
+
+const (
+	MatchThree MatchShape = iota
+	MatchFour
+	MatchFive // five or more in a row, whether or not another run crosses it
+	MatchL    // two runs meeting at the end of each
+	MatchT    // two runs where one meets the other away from its end, including a cross
+)
+
+const (
+	gemScore        = 10 // points for each gem in a match
+	maxCascadeSteps = 50 // a cascade this long is stopped, refills are random so it could go on
+)
+
+// matchBonus is the points for a match on top of the points for its gems
+var matchBonus = map[MatchShape]int{
+	MatchThree: 0,
+	MatchFour:  20,
+	MatchL:     30,
+	MatchT:     40,
+	MatchFive:  60,
+}
+
+// Match is a group of identical gems that are cleared together.
+type Match struct {
+	cells  []Coord // in reading order
+	corner Coord   // where the first two runs cross, -1, -1 for a single run
+	gem    int
+	shape  MatchShape
+}
+
+// Score returns the points for the match before any chain multiplier
+func (m Match) Score() int {
+	return gemScore*len(m.cells) + matchBonus[m.shape]
+}
+
+// Special returns the kind of special gem the match leaves behind
+func (m Match) Special() GemKind {
+	switch m.shape {
+	case MatchFour:
+		return GemLineClear
+	case MatchL, MatchT:
+		return GemBomb
+	case MatchFive:
+		return GemColourBomb
+	}
+	return GemNormal
+}
+
+// Center returns the square where the special gem of the match is left, the corner of an L
+// or where the runs of a T cross, otherwise the middle of the run.
+func (m Match) Center() Coord {
+	if m.corner.x >= 0 {
+		return m.corner
+	}
+	return m.cells[len(m.cells)/2]
+}
+
+// CascadeStep is one round of resolving the board: the matches cleared, then the gems that moved
+// and were spawned to refill the board.
+type CascadeStep struct {
+	matches    []Match
+	events     []BoardEvent
+	board      *Board // the board after the step
+	multiplier int    // 1 for the first step, 2 for the next and so on
+	score      int    // the points for the matches multiplied by the multiplier
+}
+
+// run is a line of three or more identical gems
+type run struct {
+	start      Coord
+	length     int
+	horizontal bool
+}
+
+func (r run) cell(i int) Coord {
+	if r.horizontal {
+		return Coord{r.start.x + i, r.start.y}
+	}
+	return Coord{r.start.x, r.start.y + i}
+}
+
+func (r run) contains(p Coord) bool {
+	if r.horizontal {
+		return p.y == r.start.y && p.x >= r.start.x && p.x < r.start.x+r.length
+	}
+	return p.x == r.start.x && p.y >= r.start.y && p.y < r.start.y+r.length
+}
+
+// isEnd returns true if p is the first or last square of the run
+func (r run) isEnd(p Coord) bool {
+	return p == r.cell(0) || p == r.cell(r.length-1)
+}
+
+// crossing returns the square shared by two runs, if they share one
+func (r run) crossing(other run) (Coord, bool) {
+	for i := range r.length {
+		if other.contains(r.cell(i)) {
+			return r.cell(i), true
+		}
+	}
+	return Coord{-1, -1}, false
+}
+
+// runs finds the runs of three or more identical gems in the rows and columns of the board
+func (b *Board) runs() []run {
+	var runs []run
+	find := func(start Coord, step Coord, n int, horizontal bool) {
+		length := 1
+		for i := 1; i <= n; i++ {
+			p := Coord{start.x + step.x*i, start.y + step.y*i}
+			prev := Coord{p.x - step.x, p.y - step.y}
+			if i < n && b.gems.Get(p) >= 0 && b.gems.Get(p) == b.gems.Get(prev) {
+				length++
+				continue
+			}
+			if length >= 3 && b.gems.Get(prev) >= 0 {
+				runs = append(runs, run{
+					start:      Coord{prev.x - step.x*(length-1), prev.y - step.y*(length-1)},
+					length:     length,
+					horizontal: horizontal,
+				})
+			}
+			length = 1
+		}
+	}
+	for y := range b.Rows() {
+		find(Coord{0, y}, Coord{1, 0}, b.Columns(), true)
+	}
+	for x := range b.Columns() {
+		find(Coord{x, 0}, Coord{0, 1}, b.Rows(), false)
+	}
+	return runs
+}
+
+// Matches finds the matches on the board. Runs that cross are joined into a single match.
+func (b *Board) Matches() []Match {
+	runs := b.runs()
+	var matches []Match
+	used := make([]bool, len(runs))
+	for i := range runs {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		group := []run{runs[i]}
+		for k := 0; k < len(group); k++ {
+			for j := range runs {
+				if _, crosses := group[k].crossing(runs[j]); crosses && !used[j] {
+					used[j] = true
+					group = append(group, runs[j])
+				}
+			}
+		}
+		matches = append(matches, b.newMatch(group))
+	}
+	return matches
+}
+
+// newMatch makes a match of a group of crossing runs, classifying its shape
+func (b *Board) newMatch(group []run) Match {
+	m := Match{gem: b.gems.Get(group[0].start), corner: Coord{-1, -1}}
+	if len(group) > 1 {
+		m.corner, _ = group[0].crossing(group[1])
+	}
+	longest := 0
+	for _, r := range group {
+		longest = max(longest, r.length)
+		for i := range r.length {
+			if p := r.cell(i); !slices.Contains(m.cells, p) {
+				m.cells = append(m.cells, p)
+			}
+		}
+	}
+	slices.SortFunc(m.cells, func(p, q Coord) int {
+		if p.y != q.y {
+			return p.y - q.y
+		}
+		return p.x - q.x
+	})
+
+	switch {
+	case longest >= 5:
+		m.shape = MatchFive
+	case len(group) == 1 && longest == 4:
+		m.shape = MatchFour
+	case len(group) == 1:
+		m.shape = MatchThree
+	default:
+		m.shape = MatchL
+		for _, other := range group[1:] {
+			p, _ := group[0].crossing(other)
+			if !group[0].isEnd(p) || !other.isEnd(p) {
+				m.shape = MatchT
+			}
+		}
+	}
+	return m
+}
+
+// Resolve clears the matches on the board, collapses and refills it, and repeats until the board
+// has no matches. It returns the steps taken, which are empty if there were no matches.
+func (b *Board) Resolve() []CascadeStep {
+	var steps []CascadeStep
+	for chain := 1; chain <= maxCascadeSteps; chain++ {
+		matches := b.Matches()
+		if len(matches) == 0 {
+			break
+		}
+		step := CascadeStep{matches: matches, multiplier: chain}
+		for _, m := range matches {
+			step.score += m.Score()
+		}
+		step.events = b.RemoveMatches()
+		for _, e := range step.events {
+			if e.kind == GemBlasted {
+				step.score += gemScore
+			}
+		}
+		step.score *= chain
+		step.events = append(step.events, b.Collapse()...)
+		step.events = append(step.events, b.Refill()...)
+		step.board = b.Copy()
+		steps = append(steps, step)
+	}
+	return steps
+}
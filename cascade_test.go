@@ -0,0 +1,265 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMatchShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  [][]int
+		shape MatchShape
+		cells int
+	}{
+		{"Three in a row", [][]int{
+			{0, 0, 0, 1, 2},
+		}, MatchThree, 3},
+		{"Four in a column", [][]int{
+			{0, 1, 2, 3, 4},
+			{0, 2, 1, 4, 3},
+			{0, 1, 2, 3, 4},
+			{0, 2, 1, 4, 3},
+		}, MatchFour, 4},
+		{"Five in a row", [][]int{
+			{0, 0, 0, 0, 0},
+		}, MatchFive, 5},
+		{"L", [][]int{
+			{0, 1, 2, 3, 4},
+			{0, 2, 1, 4, 3},
+			{0, 0, 0, 3, 4},
+		}, MatchL, 5},
+		{"T", [][]int{
+			{0, 0, 0, 3, 4},
+			{1, 0, 1, 4, 3},
+			{2, 0, 2, 3, 4},
+		}, MatchT, 5},
+		{"Cross", [][]int{
+			{1, 0, 2, 3, 4},
+			{0, 0, 0, 4, 3},
+			{2, 0, 1, 3, 4},
+		}, MatchT, 5},
+		{"Five with a crossing run", [][]int{
+			{0, 0, 0, 0, 0},
+			{1, 0, 1, 4, 3},
+			{2, 0, 2, 3, 4},
+		}, MatchFive, 7},
+	}
+
+	for _, tt := range tests {
+		matches := newTestBoard(tt.rows).Matches()
+		if len(matches) != 1 {
+			t.Errorf("%s: found %d matches; want 1", tt.name, len(matches))
+			continue
+		}
+		if m := matches[0]; m.shape != tt.shape || len(m.cells) != tt.cells || m.gem != 0 {
+			t.Errorf("%s: match is shape %d of %d gem %d; want shape %d of %d gem 0",
+				tt.name, m.shape, len(m.cells), m.gem, tt.shape, tt.cells)
+		}
+	}
+}
+
+func TestMatchesAreSeparate(t *testing.T) {
+	b := newTestBoard([][]int{
+		{0, 0, 0, 1, 1, 1},
+		{2, 3, 2, 3, 2, 3},
+	})
+	matches := b.Matches()
+	if len(matches) != 2 {
+		t.Fatalf("found %d matches; want 2", len(matches))
+	}
+	for _, m := range matches {
+		if m.shape != MatchThree {
+			t.Errorf("match of gem %d is shape %d; want MatchThree", m.gem, m.shape)
+		}
+	}
+}
+
+func TestResolveChain(t *testing.T) {
+	// clearing the column of 0s moves the 1s below it up to make a column of 1s
+	b := newTestBoard([][]int{
+		{2, 3, 1, 3},
+		{3, 2, 0, 2},
+		{2, 3, 0, 3},
+		{3, 2, 0, 2},
+		{2, 3, 1, 3},
+		{3, 2, 1, 2},
+	})
+	steps := b.Resolve()
+	if len(steps) < 2 {
+		t.Fatalf("Resolve() took %d steps; want at least 2", len(steps))
+	}
+
+	first := steps[0]
+	if len(first.matches) != 1 || first.matches[0].gem != 0 {
+		t.Errorf("first step matched %v; want the column of 0s", first.matches)
+	}
+	if first.multiplier != 1 || first.score != 3*gemScore {
+		t.Errorf("first step multiplier %d score %d; want 1 and %d", first.multiplier, first.score, 3*gemScore)
+	}
+
+	second := steps[1]
+	found := false
+	for _, m := range second.matches {
+		if m.gem == 1 && m.cells[0] == (Coord{2, 0}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("second step did not match the column of 1s")
+	}
+	if second.multiplier != 2 {
+		t.Errorf("second step multiplier = %d; want 2", second.multiplier)
+	}
+
+	for i, step := range steps {
+		want := 0
+		for _, m := range step.matches {
+			want += m.Score()
+		}
+		if step.multiplier != i+1 || step.score != want*(i+1) {
+			t.Errorf("step %d multiplier %d score %d; want %d and %d", i, step.multiplier, step.score, i+1, want*(i+1))
+		}
+	}
+	if found, _ := b.FindMatches(); found {
+		t.Errorf("board has matches after Resolve()")
+	}
+	if !equals(b.gems[0], steps[len(steps)-1].board.gems[0]) {
+		t.Errorf("last step does not record the board after it")
+	}
+}
+
+func TestResolveLeavesStableBoards(t *testing.T) {
+	for seed := range int64(20) {
+		b := NewBoard(8, numGemRows, 4, rand.New(rand.NewSource(seed)))
+		b.FillRandom()
+		b.Resolve()
+		if found, _ := b.FindMatches(); found {
+			t.Errorf("seed %d: board has matches after Resolve()", seed)
+		}
+		b.gems.ForEach(func(p Coord, gem int) {
+			if gem == emptyGem {
+				t.Errorf("seed %d: square %v is empty after Resolve()", seed, p)
+			}
+		})
+	}
+}
+
+func TestRemoveMatchesMakesSpecialGems(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]int
+		at   Coord
+		kind GemKind
+	}{
+		{"Three leaves nothing", [][]int{
+			{0, 0, 0, 1, 2},
+		}, Coord{1, 0}, GemNormal},
+		{"Four makes a line clear", [][]int{
+			{0, 0, 0, 0, 2},
+		}, Coord{2, 0}, GemLineClear},
+		{"L makes a bomb at the corner", [][]int{
+			{0, 1, 2, 3, 4},
+			{0, 2, 1, 4, 3},
+			{0, 0, 0, 3, 4},
+		}, Coord{0, 2}, GemBomb},
+		{"Five makes a colour bomb", [][]int{
+			{0, 0, 0, 0, 0},
+		}, Coord{2, 0}, GemColourBomb},
+	}
+
+	for _, tt := range tests {
+		b := newTestBoard(tt.rows)
+		b.RemoveMatches()
+		if got := b.Kind(tt.at); got != tt.kind {
+			t.Errorf("%s: kind at %v = %d; want %d", tt.name, tt.at, got, tt.kind)
+		}
+		if tt.kind != GemNormal && b.Gem(tt.at) != 0 {
+			t.Errorf("%s: special gem is %d; want the colour of the match 0", tt.name, b.Gem(tt.at))
+		}
+		if tt.kind == GemNormal && b.Gem(tt.at) != emptyGem {
+			t.Errorf("%s: matched gem was not removed", tt.name)
+		}
+	}
+}
+
+func TestSpecialGemsBlast(t *testing.T) {
+	rows := [][]int{
+		{1, 2, 3, 4, 1},
+		{2, 0, 4, 1, 2},
+		{3, 4, 1, 2, 3},
+		{4, 1, 2, 3, 4},
+	}
+	tests := []struct {
+		name    string
+		kinds   map[Coord]GemKind
+		deleted Coord
+		cleared []Coord
+	}{
+		{"Normal gem", nil, Coord{1, 1}, []Coord{{1, 1}}},
+		{"Line clear", map[Coord]GemKind{{1, 1}: GemLineClear}, Coord{1, 1},
+			[]Coord{{0, 1}, {1, 1}, {2, 1}, {3, 1}, {4, 1}}},
+		{"Bomb", map[Coord]GemKind{{1, 1}: GemBomb}, Coord{1, 1},
+			[]Coord{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}},
+		{"Colour bomb", map[Coord]GemKind{{0, 0}: GemColourBomb}, Coord{0, 0},
+			[]Coord{{0, 0}, {4, 0}, {3, 1}, {2, 2}, {1, 3}}},
+		{"Line clear sets off a bomb", map[Coord]GemKind{{0, 2}: GemLineClear, {4, 2}: GemBomb}, Coord{0, 2},
+			[]Coord{{3, 1}, {4, 1}, {0, 2}, {1, 2}, {2, 2}, {3, 2}, {4, 2}, {3, 3}, {4, 3}}},
+	}
+
+	for _, tt := range tests {
+		b := newTestBoard(rows)
+		for p, kind := range tt.kinds {
+			b.SetKind(p, kind)
+		}
+		events := b.Delete(BoardRange{tt.deleted, tt.deleted})
+		for y := range len(rows) {
+			for x := range len(rows[0]) {
+				p := Coord{x, y}
+				want := slices.Contains(tt.cleared, p)
+				if got := b.Gem(p) == emptyGem; got != want {
+					t.Errorf("%s: square %v cleared = %v; want %v", tt.name, p, got, want)
+				}
+			}
+		}
+		if len(events) != len(tt.cleared) {
+			t.Errorf("%s: Delete() returned %d events; want %d", tt.name, len(events), len(tt.cleared))
+		}
+		for _, e := range events {
+			if want := e.to != tt.deleted; (e.kind == GemBlasted) != want {
+				t.Errorf("%s: event at %v has kind %d", tt.name, e.to, e.kind)
+			}
+		}
+	}
+}
+
+func TestMatchSetsOffSpecialGem(t *testing.T) {
+	b := newTestBoard([][]int{
+		{0, 0, 0, 1, 2},
+		{3, 4, 3, 4, 3},
+	})
+	// the line clear in the match clears the row, setting off the bomb at its end
+	b.SetKind(Coord{1, 0}, GemLineClear)
+	b.SetKind(Coord{4, 0}, GemBomb)
+	events := b.RemoveMatches()
+
+	cleared := []Coord{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {3, 1}, {4, 1}}
+	for y := range 2 {
+		for x := range 5 {
+			p := Coord{x, y}
+			if got, want := b.Gem(p) == emptyGem, slices.Contains(cleared, p); got != want {
+				t.Errorf("square %v cleared = %v; want %v", p, got, want)
+			}
+		}
+	}
+	blasted := 0
+	for _, e := range events {
+		if e.kind == GemBlasted {
+			blasted++
+		}
+	}
+	if blasted != 4 {
+		t.Errorf("%d gems were blasted; want 4", blasted)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+/*
+ * Game time. GameClock moves on by a fixed step each update, scaled and paused with the game,
+ * and FakeClock is moved on by tests.
+ */
+
+// tick is the game time the speeds of things that move are given for
+const tick = time.Second / 60
+
+// Clock tells the levels the game time
+type Clock interface {
+	Now() time.Duration  // game time since the game started
+	Step() time.Duration // game time the last update moved on by, 0 while paused
+}
+
+// GameClock is the clock of the game, moved on by each update
+type GameClock struct {
+	now    time.Duration
+	step   time.Duration
+	paused bool
+	scale  float64
+}
+
+// NewGameClock returns a clock at full speed
+func NewGameClock() *GameClock {
+	return &GameClock{scale: 1}
+}
+
+// Tick moves the clock on by the fixed step of an update, 1/TPS of a second times the scale,
+// unless it is paused
+func (c *GameClock) Tick() {
+	c.step = 0
+	if !c.paused {
+		c.step = time.Duration(float64(time.Second/time.Duration(ebiten.TPS())) * c.scale)
+	}
+	c.now += c.step
+}
+
+func (c *GameClock) Now() time.Duration {
+	return c.now
+}
+
+func (c *GameClock) Step() time.Duration {
+	return c.step
+}
+
+// Pause stops the clock until it is resumed
+func (c *GameClock) Pause() {
+	c.paused = true
+}
+
+// Resume starts a paused clock
+func (c *GameClock) Resume() {
+	c.paused = false
+}
+
+// Paused returns true while the clock is paused
+func (c *GameClock) Paused() bool {
+	return c.paused
+}
+
+// SetScale sets how fast game time passes, 1 is real time and 0.5 half speed
+func (c *GameClock) SetScale(scale float64) {
+	c.scale = max(scale, 0)
+}
+
+// FakeClock is a clock for tests, it only moves on when told to
+type FakeClock struct {
+	now  time.Duration
+	step time.Duration
+}
+
+// Advance moves the clock on by d, as an update of d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.step = d
+	c.now += d
+}
+
+func (c *FakeClock) Now() time.Duration {
+	return c.now
+}
+
+func (c *FakeClock) Step() time.Duration {
+	return c.step
+}
+
+// ticks returns the number of ticks in d, to move things given a speed in pixels a tick
+func ticks(d time.Duration) float64 {
+	return float64(d) / float64(tick)
+}
+
+// blinkPhase returns 0 and 1 in turn, changing each interval, to flash cursors and warnings
+func blinkPhase(now, interval time.Duration) int {
+	return int(now / interval % 2)
+}
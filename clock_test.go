@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameClock(t *testing.T) {
+	c := NewGameClock()
+	c.Tick()
+	if c.Now() != tick || c.Step() != tick {
+		t.Errorf("after a tick at full speed Now() = %v, Step() = %v, want %v", c.Now(), c.Step(), tick)
+	}
+	c.Pause()
+	c.Tick()
+	if c.Now() != tick || c.Step() != 0 || !c.Paused() {
+		t.Errorf("a paused clock moved on to %v by %v", c.Now(), c.Step())
+	}
+	c.Resume()
+	c.SetScale(0.5)
+	c.Tick()
+	if c.Step() != tick/2 || c.Now() != tick+tick/2 {
+		t.Errorf("at half speed a tick moved on by %v to %v, want %v to %v", c.Step(), c.Now(), tick/2, tick+tick/2)
+	}
+	c.SetScale(-1)
+	c.Tick()
+	if c.Step() != 0 {
+		t.Errorf("a negative scale moved the clock by %v", c.Step())
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	var c FakeClock
+	c.Advance(time.Second)
+	c.Advance(250 * time.Millisecond)
+	if c.Now() != 1250*time.Millisecond || c.Step() != 250*time.Millisecond {
+		t.Errorf("Now() = %v, Step() = %v, want 1.25s, 250ms", c.Now(), c.Step())
+	}
+}
+
+func TestBlinkPhase(t *testing.T) {
+	interval := time.Second / 3
+	tests := []struct {
+		now  time.Duration
+		want int
+	}{
+		{0, 0},
+		{interval - 1, 0},
+		{interval, 1},
+		{2*interval - 1, 1},
+		{2 * interval, 0},
+	}
+	for _, tt := range tests {
+		if got := blinkPhase(tt.now, interval); got != tt.want {
+			t.Errorf("blinkPhase(%v) = %d, want %d", tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import "math"
+
+/*
+ * Swept collisions between a moving circle and rectangles. Rather than moving the ball and then
+ * testing for overlap, which lets a fast ball pass through thin bricks or touch several bricks
+ * at once, the path of the ball over a step is tested against each rectangle to find the time
+ * it first touches it. The ball is moved to the earliest hit, reflected, and the rest of the
+ * step is tested again.
+ */
+
+// Rect is a rectangle with sides parallel to the screen edges
+type Rect struct {
+	left, top, width, height float32
+}
+
+func (r Rect) right() float32 {
+	return r.left + r.width
+}
+
+func (r Rect) bottom() float32 {
+	return r.top + r.height
+}
+
+// Collision is where a moving circle first touches a rectangle
+type Collision struct {
+	t      float32 // the part of the motion made before the hit, 0 to 1
+	nx, ny float32 // unit normal of the surface hit, pointing away from the rectangle
+}
+
+// sweepCircle finds when a circle of radius at x, y moving by dx, dy first touches the rectangle.
+// A circle already touching the rectangle hits it at once if it is moving towards it, so a paddle
+// moved onto the ball still sends it back.
+func sweepCircle(x, y, dx, dy, radius float32, r Rect) (Collision, bool) {
+	if c, touching := touchCircle(x, y, radius, r); touching {
+		return c, dx*c.nx+dy*c.ny < 0
+	}
+
+	// the path of the center against the rectangle grown by the radius on every side
+	tEnterX, tExitX, okX := slab(x, dx, r.left-radius, r.right()+radius)
+	tEnterY, tExitY, okY := slab(y, dy, r.top-radius, r.bottom()+radius)
+	if !okX || !okY {
+		return Collision{}, false
+	}
+	tEnter := max(tEnterX, tEnterY)
+	tExit := min(tExitX, tExitY)
+	if tEnter > tExit || tEnter > 1 || tExit < 0 {
+		return Collision{}, false
+	}
+	tEnter = max(tEnter, 0)
+
+	px, py := x+dx*tEnter, y+dy*tEnter
+	if (px < r.left || px > r.right()) && (py < r.top || py > r.bottom()) {
+		// entering by a corner of the grown rectangle, which is really a quarter circle
+		cornerX, cornerY := r.left, r.top
+		if px > r.right() {
+			cornerX = r.right()
+		}
+		if py > r.bottom() {
+			cornerY = r.bottom()
+		}
+		return sweepPoint(x, y, dx, dy, radius, cornerX, cornerY)
+	}
+
+	c := Collision{t: tEnter}
+	if tEnterX > tEnterY {
+		c.nx = -sign(dx)
+	} else {
+		c.ny = -sign(dy)
+	}
+	return c, true
+}
+
+// touchCircle returns the normal of the nearest side if the circle overlaps the rectangle
+func touchCircle(x, y, radius float32, r Rect) (Collision, bool) {
+	closestX := limitToRange(x, r.left, r.right())
+	closestY := limitToRange(y, r.top, r.bottom())
+	distX, distY := x-closestX, y-closestY
+	dist := float32(math.Hypot(float64(distX), float64(distY)))
+	if dist >= radius {
+		return Collision{}, false
+	}
+	if dist > 0 {
+		return Collision{nx: distX / dist, ny: distY / dist}, true
+	}
+
+	// the center is inside, push it out of the side it is closest to
+	c := Collision{nx: -1}
+	nearest := x - r.left
+	if d := r.right() - x; d < nearest {
+		nearest, c = d, Collision{nx: 1}
+	}
+	if d := y - r.top; d < nearest {
+		nearest, c = d, Collision{ny: -1}
+	}
+	if d := r.bottom() - y; d < nearest {
+		c = Collision{ny: 1}
+	}
+	return c, true
+}
+
+// slab returns the times the path from p moving by d is between low and high, false if never
+func slab(p, d, low, high float32) (float32, float32, bool) {
+	if d == 0 {
+		inside := p >= low && p <= high
+		return float32(math.Inf(-1)), float32(math.Inf(1)), inside
+	}
+	t1, t2 := (low-p)/d, (high-p)/d
+	return min(t1, t2), max(t1, t2), true
+}
+
+// sweepPoint finds when a circle moving by dx, dy first touches the point px, py
+func sweepPoint(x, y, dx, dy, radius, px, py float32) (Collision, bool) {
+	fx, fy := float64(x-px), float64(y-py)
+	a := float64(dx*dx + dy*dy)
+	b := 2 * (fx*float64(dx) + fy*float64(dy))
+	c := fx*fx + fy*fy - float64(radius*radius)
+	disc := b*b - 4*a*c
+	if a == 0 || disc < 0 {
+		return Collision{}, false
+	}
+	t := float32((-b - math.Sqrt(disc)) / (2 * a))
+	if t < 0 || t > 1 {
+		return Collision{}, false
+	}
+	nx, ny := x+dx*t-px, y+dy*t-py
+	length := float32(math.Hypot(float64(nx), float64(ny)))
+	hit := Collision{t: t, nx: nx / length, ny: ny / length}
+	// a circle that only grazes the point is not turned
+	return hit, dx*hit.nx+dy*hit.ny < 0
+}
+
+// bounce returns the velocity after bouncing off the surface of the collision
+func bounce(dx, dy float32, c Collision) (float32, float32) {
+	d := dx*c.nx + dy*c.ny
+	return dx - 2*d*c.nx, dy - 2*d*c.ny
+}
+
+// enforceMinimumSpeed scales up a velocity that is slower than speed, leaving a still ball still
+func enforceMinimumSpeed(dx, dy float32, speed float64) (float32, float32) {
+	current := math.Abs(float64(dx)) + math.Abs(float64(dy))
+	if current == 0 || current >= speed {
+		return dx, dy
+	}
+	scale := float32(speed / current)
+	return dx * scale, dy * scale
+}
+
+func sign(f float32) float32 {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
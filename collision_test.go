@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSweepCircle(t *testing.T) {
+	brick := Rect{100, 100, 100, 50}
+	tests := []struct {
+		name           string
+		x, y, dx, dy   float32
+		hit            bool
+		t              float32
+		wantNX, wantNY float32
+	}{
+		{"Straight down onto the top", 150, 80, 0, 20, true, 0.5, 0, -1},
+		{"Straight up into the bottom", 150, 170, 0, -20, true, 0.5, 0, 1},
+		{"Across into the left side", 80, 125, 20, 0, true, 0.5, -1, 0},
+		{"Falls short", 150, 80, 0, 5, false, 0, 0, 0},
+		{"Moving away", 150, 80, 0, -20, false, 0, 0, 0},
+		{"Passes beside", 80, 80, 0, 100, false, 0, 0, 0},
+		{"Corner hit on the diagonal", 90, 90, 10, 10, true, 1 - float32(10/math.Sqrt2)/10, -float32(1 / math.Sqrt2), -float32(1 / math.Sqrt2)},
+		{"Misses the corner", 88, 80, 10, 10, false, 0, 0, 0},
+		{"Grazes the top", 50, 90, 200, 0, false, 0, 0, 0},
+		{"Grazes the corner", 90, 50, 0, 100, false, 0, 0, 0},
+		{"High speed does not tunnel", 150, 0, 0, 500, true, 0.18, 0, -1},
+		{"High speed across", 0, 125, 1000, 0, true, 0.09, -1, 0},
+		{"Already touching and moving in", 150, 95, 0, 5, true, 0, 0, -1},
+		{"Already touching and moving out", 150, 95, 0, -5, false, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hit := sweepCircle(tt.x, tt.y, tt.dx, tt.dy, 10, brick)
+			if hit != tt.hit {
+				t.Fatalf("sweepCircle() hit = %v, want %v", hit, tt.hit)
+			}
+			if !hit {
+				return
+			}
+			if !near(got.t, tt.t) || !near(got.nx, tt.wantNX) || !near(got.ny, tt.wantNY) {
+				t.Errorf("sweepCircle() = %+v, want t %v normal %v, %v", got, tt.t, tt.wantNX, tt.wantNY)
+			}
+		})
+	}
+}
+
+func TestBounce(t *testing.T) {
+	tests := []struct {
+		name           string
+		dx, dy         float32
+		c              Collision
+		wantDX, wantDY float32
+	}{
+		{"Off a floor", 3, 4, Collision{ny: -1}, 3, -4},
+		{"Off a wall", 3, 4, Collision{nx: 1}, -3, 4},
+		{"Off a corner head on", 1, 1, Collision{nx: -float32(1 / math.Sqrt2), ny: -float32(1 / math.Sqrt2)}, -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dx, dy := bounce(tt.dx, tt.dy, tt.c)
+			if !near(dx, tt.wantDX) || !near(dy, tt.wantDY) {
+				t.Errorf("bounce() = %v, %v, want %v, %v", dx, dy, tt.wantDX, tt.wantDY)
+			}
+		})
+	}
+}
+
+func TestEnforceMinimumSpeed(t *testing.T) {
+	tests := []struct {
+		name           string
+		dx, dy         float32
+		wantDX, wantDY float32
+	}{
+		{"Fast enough", 2, 2, 2, 2},
+		{"Too slow", 1, 0.5, 2, 1},
+		{"Horizontal", -1, 0, -3, 0},
+		{"Still", 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dx, dy := enforceMinimumSpeed(tt.dx, tt.dy, 3)
+			if !near(dx, tt.wantDX) || !near(dy, tt.wantDY) {
+				t.Errorf("enforceMinimumSpeed() = %v, %v, want %v, %v", dx, dy, tt.wantDX, tt.wantDY)
+			}
+		})
+	}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestTouchCircle(t *testing.T) {
+	square := Rect{100, 100, 100, 100}
+	tests := []struct {
+		name string
+		x, y float32
+		want bool
+	}{
+		{"Above", 105, 85, false},
+		{"Above, radius touching", 105, 95, true},
+		{"Left", 85, 105, false},
+		{"Left, radius touching", 95, 105, true},
+		{"Inside", 195, 105, true},
+		{"Right, radius touching", 205, 195, true},
+		{"Right", 215, 195, false},
+		{"Below, radius touching", 195, 205, true},
+		{"Below", 195, 215, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := touchCircle(tt.x, tt.y, 10, square); got != tt.want {
+				t.Errorf("touchCircle(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+ * Parsing of normal mode commands typed one character at a time, such as "3j", "ma",
+ * "`a" or "dd". A level lists the commands it accepts and feeds the typed characters
+ * to parseCommand until a command is complete or the input can no longer match.
+ */
+
+// commandSpec describes a normal mode command accepted by a level.
+type commandSpec struct {
+	keys     string // the characters of the command after any count, e.g. "dd" or "zz"
+	argument bool   // true if a character follows the keys, e.g. the mark letter in "ma"
+}
+
+// viCommand is a complete normal mode command.
+type viCommand struct {
+	count    int // the count typed before the command, 0 if none was typed
+	keys     string
+	argument rune // the character following the keys, if the command takes one
+}
+
+// Count returns the count of the command, treating a missing count as 1.
+func (c viCommand) Count() int {
+	return max(c.count, 1)
+}
+
+// commandParseResult is the state of a partially typed command.
+type commandParseResult int
+
+const (
+	commandIncomplete commandParseResult = iota
+	commandComplete
+	commandInvalid
+)
+
+// parseCommand parses typed, the characters entered since the last command, against the commands
+// in specs. A leading count is only recognised when the first digit is not 0, so 0 can be a command.
+func parseCommand(typed string, specs []commandSpec) (viCommand, commandParseResult) {
+	var cmd viCommand
+
+	digits := 0
+	for digits < len(typed) && isDigit(typed[digits]) && !(digits == 0 && typed[0] == '0') {
+		digits++
+	}
+	if digits > 0 {
+		cmd.count, _ = strconv.Atoi(typed[:digits])
+	}
+	rest := typed[digits:]
+	if rest == "" {
+		return cmd, commandIncomplete
+	}
+
+	incomplete := false
+	for _, spec := range specs {
+		switch {
+		case rest == spec.keys && !spec.argument:
+			cmd.keys = spec.keys
+			return cmd, commandComplete
+		case spec.argument && len(rest) > len(spec.keys) && strings.HasPrefix(rest, spec.keys):
+			argument := []rune(rest[len(spec.keys):])
+			if len(argument) == 1 {
+				cmd.keys = spec.keys
+				cmd.argument = argument[0]
+				return cmd, commandComplete
+			}
+		case strings.HasPrefix(spec.keys, rest):
+			incomplete = true
+		}
+	}
+	if incomplete {
+		return cmd, commandIncomplete
+	}
+	return cmd, commandInvalid
+}
+
+// startsCommand returns true if ch can be the first character typed for one of the commands in specs.
+func startsCommand(ch rune, specs []commandSpec) bool {
+	if ch >= '1' && ch <= '9' {
+		return true
+	}
+	for _, spec := range specs {
+		if strings.HasPrefix(spec.keys, string(ch)) {
+			return true
+		}
+	}
+	return false
+}
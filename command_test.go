@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestParseCommand(t *testing.T) {
+	specs := []commandSpec{
+		{keys: "j"}, {keys: "0"}, {keys: "dd"}, {keys: "gg"}, {keys: "G"},
+		{keys: "m", argument: true},
+		{keys: "'", argument: true},
+	}
+	tests := []struct {
+		typed  string
+		want   viCommand
+		result commandParseResult
+	}{
+		{"j", viCommand{keys: "j"}, commandComplete},
+		{"12j", viCommand{count: 12, keys: "j"}, commandComplete},
+		{"0", viCommand{keys: "0"}, commandComplete},
+		{"10", viCommand{count: 10}, commandIncomplete},
+		{"d", viCommand{}, commandIncomplete},
+		{"dd", viCommand{keys: "dd"}, commandComplete},
+		{"3dd", viCommand{count: 3, keys: "dd"}, commandComplete},
+		{"g", viCommand{}, commandIncomplete},
+		{"5G", viCommand{count: 5, keys: "G"}, commandComplete},
+		{"m", viCommand{}, commandIncomplete},
+		{"ma", viCommand{keys: "m", argument: 'a'}, commandComplete},
+		{"''", viCommand{keys: "'", argument: '\''}, commandComplete},
+		{"x", viCommand{}, commandInvalid},
+		{"dj", viCommand{}, commandInvalid},
+		{"3x", viCommand{count: 3}, commandInvalid},
+	}
+
+	for _, tt := range tests {
+		got, result := parseCommand(tt.typed, specs)
+		if result != tt.result || (result == commandComplete && got != tt.want) {
+			t.Errorf("parseCommand(%q) = %+v, %v; want %+v, %v", tt.typed, got, result, tt.want, tt.result)
+		}
+	}
+}
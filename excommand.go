@@ -0,0 +1,435 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Parsing and execution of ex commands typed on the command line after ':'.
+ * Only the parts of ex used by the levels are supported: line ranges and the
+ * substitute command, :[range]s/pattern/replacement/[flags].
+ */
+
+var (
+	errBackwardsRange  = errors.New("E493: Backwards range given")
+	errInvalidPattern  = errors.New("E383: Invalid search string")
+	errInvalidRange    = errors.New("E16: Invalid range")
+	errMarkNotSet      = errors.New("E20: Mark not set")
+	errNoPattern       = errors.New("E35: No previous regular expression")
+	errNotAnEditorCmd  = errors.New("E492: Not an editor command")
+	errPatternNotFound = errors.New("E486: Pattern not found")
+	errTrailingChars   = errors.New("E488: Trailing characters")
+)
+
+// exContext holds the editor state that ex addresses are resolved against.
+// Lines are 0 based, so the first line of the buffer is line 0 and is typed as 1.
+type exContext struct {
+	cursor   int
+	lastLine int
+	marks    map[rune]int
+}
+
+// exRange is a resolved, inclusive range of lines.
+type exRange struct {
+	start, end int
+}
+
+// substitution is a parsed :s command.
+type substitution struct {
+	lines       exRange
+	pattern     *regexp.Regexp
+	replacement string
+	global      bool // g flag, replace every match in a line rather than the first
+	confirm     bool // c flag, ask before each replacement
+}
+
+// parseExAddress parses a single line address such as ".", "$", "'a", "12" or ".+2"
+// from the front of cmd. It returns the line, the unparsed remainder and whether
+// an address was present at all.
+func parseExAddress(cmd string, ctx exContext) (int, string, bool, error) {
+	line := ctx.cursor
+	found := false
+
+	switch {
+	case cmd == "":
+		return line, cmd, false, nil
+	case cmd[0] == '.':
+		cmd = cmd[1:]
+		found = true
+	case cmd[0] == '$':
+		line = ctx.lastLine
+		cmd = cmd[1:]
+		found = true
+	case cmd[0] == '\'':
+		if len(cmd) < 2 {
+			return 0, cmd, false, errMarkNotSet
+		}
+		mark, ok := ctx.marks[rune(cmd[1])]
+		if !ok {
+			return 0, cmd, false, errMarkNotSet
+		}
+		line = mark
+		cmd = cmd[2:]
+		found = true
+	case isDigit(cmd[0]):
+		n, rest := leadingNumber(cmd)
+		line = n - 1
+		cmd = rest
+		found = true
+	}
+
+	// apply any number of +n and -n offsets, a bare + or - means one line
+	for len(cmd) > 0 && (cmd[0] == '+' || cmd[0] == '-') {
+		sign := 1
+		if cmd[0] == '-' {
+			sign = -1
+		}
+		offset := 1
+		if len(cmd) > 1 && isDigit(cmd[1]) {
+			offset, cmd = leadingNumber(cmd[1:])
+		} else {
+			cmd = cmd[1:]
+		}
+		line += sign * offset
+		found = true
+	}
+	return line, cmd, found, nil
+}
+
+// parseExRange parses the range at the front of cmd and returns it with the unparsed remainder.
+// A missing range is the cursor line, "%" is the whole buffer.
+func parseExRange(cmd string, ctx exContext) (exRange, string, error) {
+	if strings.HasPrefix(cmd, "%") {
+		return exRange{0, ctx.lastLine}, cmd[1:], nil
+	}
+
+	start, rest, found, err := parseExAddress(cmd, ctx)
+	if err != nil {
+		return exRange{}, cmd, err
+	}
+	end := start
+	if strings.HasPrefix(rest, ",") {
+		if !found {
+			start = ctx.cursor
+		}
+		end, rest, _, err = parseExAddress(rest[1:], ctx)
+		if err != nil {
+			return exRange{}, cmd, err
+		}
+	}
+
+	if start < 0 || end < 0 || start > ctx.lastLine || end > ctx.lastLine {
+		return exRange{}, cmd, errInvalidRange
+	}
+	if start > end {
+		return exRange{}, cmd, errBackwardsRange
+	}
+	return exRange{start, end}, rest, nil
+}
+
+// parseSubstitute parses a complete substitute command, range included, such as "%s/old/new/g".
+func parseSubstitute(cmd string, ctx exContext) (substitution, error) {
+	var sub substitution
+
+	lines, rest, err := parseExRange(cmd, ctx)
+	if err != nil {
+		return sub, err
+	}
+	sub.lines = lines
+
+	switch {
+	case strings.HasPrefix(rest, "substitute"):
+		rest = rest[len("substitute"):]
+	case strings.HasPrefix(rest, "s"):
+		rest = rest[1:]
+	default:
+		return sub, errNotAnEditorCmd
+	}
+
+	if rest == "" {
+		return sub, errNoPattern
+	}
+	delim := rest[0]
+	if isDigit(delim) || isLetter(delim) || delim == '\\' || delim == '"' || delim == '|' || delim == ' ' {
+		return sub, errNotAnEditorCmd
+	}
+
+	pattern, rest := splitAtDelimiter(rest[1:], delim)
+	replacement, flags := splitAtDelimiter(rest, delim)
+	if pattern == "" {
+		return sub, errNoPattern
+	}
+
+	ignoreCase := false
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			sub.global = true
+		case 'c':
+			sub.confirm = true
+		case 'i':
+			ignoreCase = true
+		case 'I':
+			ignoreCase = false
+		case ' ':
+			// trailing spaces are allowed
+		default:
+			return sub, errTrailingChars
+		}
+	}
+
+	expr := viPatternToRegexp(pattern)
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	sub.pattern, err = regexp.Compile(expr)
+	if err != nil {
+		return sub, errInvalidPattern
+	}
+	sub.replacement = replacement
+	return sub, nil
+}
+
+// Apply performs the substitution on every line of the range without asking for confirmation.
+// It returns the number of replacements made.
+func (s substitution) Apply(lines []string) int {
+	count := 0
+	for y := s.lines.start; y <= s.lines.end && y < len(lines); y++ {
+		var n int
+		lines[y], n = s.replaceLine(lines[y])
+		count += n
+	}
+	return count
+}
+
+// replaceLine replaces the first match, or every match with the g flag, in a single line.
+func (s substitution) replaceLine(line string) (string, int) {
+	matches := s.pattern.FindAllStringSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return line, 0
+	}
+	if !s.global {
+		matches = matches[:1]
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(line[last:m[0]])
+		b.WriteString(expandReplacement(s.replacement, line, m))
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String(), len(matches)
+}
+
+// substituteConfirm steps through the matches of a substitution made with the c flag,
+// one match at a time, so the player can answer y, n, a, q or l for each.
+type substituteConfirm struct {
+	sub  substitution
+	line int
+	col  int // search for the next match from this byte offset
+	done bool
+	made int // number of replacements made
+}
+
+func newSubstituteConfirm(sub substitution, lines []string) *substituteConfirm {
+	c := &substituteConfirm{sub: sub, line: sub.lines.start}
+	c.seek(lines)
+	return c
+}
+
+// Current returns the line and byte offsets of the match awaiting an answer.
+func (c *substituteConfirm) Current(lines []string) (line, start, end int, ok bool) {
+	if c.done {
+		return 0, 0, 0, false
+	}
+	m := c.matchFrom(lines[c.line], c.col)
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	return c.line, m[0], m[1], true
+}
+
+// Answer applies the player's answer to the current match.
+func (c *substituteConfirm) Answer(answer rune, lines []string) {
+	switch answer {
+	case 'y':
+		c.replaceCurrent(lines)
+	case 'n':
+		c.skipCurrent(lines)
+	case 'a':
+		for !c.done {
+			c.replaceCurrent(lines)
+		}
+	case 'l':
+		c.replaceCurrent(lines)
+		c.done = true
+	case 'q':
+		c.done = true
+	}
+}
+
+// Done returns true once there are no more matches to confirm.
+func (c *substituteConfirm) Done() bool {
+	return c.done
+}
+
+// matchFrom returns the first match in line that starts at or after col.
+// The whole line is searched so that anchors such as ^ keep their meaning.
+func (c *substituteConfirm) matchFrom(line string, col int) []int {
+	for _, m := range c.sub.pattern.FindAllStringSubmatchIndex(line, -1) {
+		if m[0] >= col {
+			return m
+		}
+	}
+	return nil
+}
+
+// nextLine moves to the start of the following line in the range.
+func (c *substituteConfirm) nextLine(lines []string) {
+	c.line++
+	c.col = 0
+	c.seek(lines)
+}
+
+func (c *substituteConfirm) replaceCurrent(lines []string) {
+	line := lines[c.line]
+	m := c.matchFrom(line, c.col)
+	if m == nil {
+		c.nextLine(lines)
+		return
+	}
+	replacement := expandReplacement(c.sub.replacement, line, m)
+	lines[c.line] = line[:m[0]] + replacement + line[m[1]:]
+	c.made++
+	if !c.sub.global {
+		c.nextLine(lines)
+		return
+	}
+	c.col = m[0] + len(replacement)
+	if m[0] == m[1] {
+		// an empty match must not be found again
+		c.col++
+	}
+	c.seek(lines)
+}
+
+// seek moves forward to the next line of the range containing a match at or after col.
+func (c *substituteConfirm) seek(lines []string) {
+	for c.line <= c.sub.lines.end && c.line < len(lines) {
+		if c.matchFrom(lines[c.line], c.col) != nil {
+			return
+		}
+		c.line++
+		c.col = 0
+	}
+	c.done = true
+}
+
+func (c *substituteConfirm) skipCurrent(lines []string) {
+	m := c.matchFrom(lines[c.line], c.col)
+	if m == nil || !c.sub.global {
+		c.nextLine(lines)
+		return
+	}
+	c.col = max(m[1], m[0]+1)
+	c.seek(lines)
+}
+
+// expandReplacement builds the replacement text for one match. & and \0 insert the whole
+// match, \1 to \9 insert a group, and \& inserts a literal ampersand.
+func expandReplacement(replacement, line string, match []int) string {
+	group := func(n int) string {
+		if 2*n+1 >= len(match) || match[2*n] < 0 {
+			return ""
+		}
+		return line[match[2*n]:match[2*n+1]]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		ch := replacement[i]
+		switch {
+		case ch == '&':
+			b.WriteString(group(0))
+		case ch == '\\' && i+1 < len(replacement):
+			i++
+			if isDigit(replacement[i]) {
+				b.WriteString(group(int(replacement[i] - '0')))
+			} else {
+				b.WriteByte(replacement[i])
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// viPatternToRegexp converts a vi "magic" pattern into Go regexp syntax.
+// In vi, ( ) | + ? { } are literal unless escaped, the reverse of Go.
+func viPatternToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		if ch == '\\' && i+1 < len(pattern) {
+			i++
+			next := pattern[i]
+			switch next {
+			case '(', ')', '|', '+', '?', '{', '}':
+				b.WriteByte(next)
+			case '<', '>':
+				b.WriteString(`\b`)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(next)
+			}
+			continue
+		}
+		switch ch {
+		case '(', ')', '|', '+', '?', '{', '}':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// splitAtDelimiter returns the text before the first unescaped delimiter and the text after it.
+// An escaped delimiter is unescaped in the returned text.
+func splitAtDelimiter(s string, delim byte) (string, string) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == delim {
+			b.WriteByte(delim)
+			i++
+			continue
+		}
+		if s[i] == delim {
+			return b.String(), s[i+1:]
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), ""
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// leadingNumber parses the decimal number at the front of s.
+func leadingNumber(s string) (int, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExRange(t *testing.T) {
+	ctx := exContext{cursor: 4, lastLine: 19, marks: map[rune]int{'a': 2, 'b': 9}}
+	tests := []struct {
+		cmd     string
+		want    exRange
+		rest    string
+		wantErr error
+	}{
+		{"s/a/b/", exRange{4, 4}, "s/a/b/", nil},
+		{"%s/a/b/", exRange{0, 19}, "s/a/b/", nil},
+		{".s/a/b/", exRange{4, 4}, "s/a/b/", nil},
+		{"$s", exRange{19, 19}, "s", nil},
+		{"5,10s", exRange{4, 9}, "s", nil},
+		{".,$s", exRange{4, 19}, "s", nil},
+		{"'a,'bs", exRange{2, 9}, "s", nil},
+		{".+2s", exRange{6, 6}, "s", nil},
+		{".-1,.+1s", exRange{3, 5}, "s", nil},
+		{"+,++s", exRange{5, 6}, "s", nil},
+		{",7s", exRange{4, 6}, "s", nil},
+		{"$-3,$s", exRange{16, 19}, "s", nil},
+		{"10,5s", exRange{}, "", errBackwardsRange},
+		{"21s", exRange{}, "", errInvalidRange},
+		{"0s", exRange{}, "", errInvalidRange},
+		{"'zs", exRange{}, "", errMarkNotSet},
+	}
+
+	for _, tt := range tests {
+		got, rest, err := parseExRange(tt.cmd, ctx)
+		if err != tt.wantErr {
+			t.Errorf("parseExRange(%q) error = %v; want %v", tt.cmd, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got != tt.want || rest != tt.rest {
+			t.Errorf("parseExRange(%q) = %v, %q; want %v, %q", tt.cmd, got, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestParseSubstitute(t *testing.T) {
+	ctx := exContext{cursor: 0, lastLine: 9}
+	tests := []struct {
+		cmd         string
+		lines       exRange
+		replacement string
+		global      bool
+		confirm     bool
+		wantErr     error
+	}{
+		{"s/cat/dog/", exRange{0, 0}, "dog", false, false, nil},
+		{"s/cat/dog", exRange{0, 0}, "dog", false, false, nil},
+		{"%s/cat/dog/g", exRange{0, 9}, "dog", true, false, nil},
+		{"5,10s/cat/dog/gc", exRange{4, 9}, "dog", true, true, nil},
+		{"substitute/cat/dog/c", exRange{0, 0}, "dog", false, true, nil},
+		{"s#a/b#c/d#", exRange{0, 0}, "c/d", false, false, nil},
+		{`s/a\/b/c/`, exRange{0, 0}, "c", false, false, nil},
+		{"s/cat//", exRange{0, 0}, "", false, false, nil},
+		{"s//dog/", exRange{}, "", false, false, errNoPattern},
+		{"s", exRange{}, "", false, false, errNoPattern},
+		{"s/cat/dog/x", exRange{}, "", false, false, errTrailingChars},
+		{"d", exRange{}, "", false, false, errNotAnEditorCmd},
+		{"sacata", exRange{}, "", false, false, errNotAnEditorCmd},
+		{`s/\(cat/dog/`, exRange{}, "", false, false, errInvalidPattern},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSubstitute(tt.cmd, ctx)
+		if err != tt.wantErr {
+			t.Errorf("parseSubstitute(%q) error = %v; want %v", tt.cmd, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.lines != tt.lines || got.replacement != tt.replacement || got.global != tt.global || got.confirm != tt.confirm {
+			t.Errorf("parseSubstitute(%q) = %v %q g=%v c=%v; want %v %q g=%v c=%v", tt.cmd,
+				got.lines, got.replacement, got.global, got.confirm,
+				tt.lines, tt.replacement, tt.global, tt.confirm)
+		}
+	}
+}
+
+func TestSubstitutionApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		cmd   string
+		in    []string
+		want  []string
+		count int
+	}{
+		{
+			name:  "First match on cursor line",
+			cmd:   "s/cat/dog/",
+			in:    []string{"cat cat", "cat"},
+			want:  []string{"dog cat", "cat"},
+			count: 1,
+		},
+		{
+			name:  "Every match in the buffer",
+			cmd:   "%s/cat/dog/g",
+			in:    []string{"cat cat", "cat"},
+			want:  []string{"dog dog", "dog"},
+			count: 3,
+		},
+		{
+			name:  "Range of lines",
+			cmd:   "2,3s/a/b/g",
+			in:    []string{"aa", "aa", "aa", "aa"},
+			want:  []string{"aa", "bb", "bb", "aa"},
+			count: 4,
+		},
+		{
+			name:  "Ampersand inserts the match",
+			cmd:   "s/gem/&s/",
+			in:    []string{"one gem"},
+			want:  []string{"one gems"},
+			count: 1,
+		},
+		{
+			name:  "Groups and literal ampersand",
+			cmd:   `s/\(red\) \(blue\)/\2 \& \1/`,
+			in:    []string{"red blue"},
+			want:  []string{"blue & red"},
+			count: 1,
+		},
+		{
+			name:  "Parentheses are literal",
+			cmd:   "s/(x)/y/",
+			in:    []string{"f(x)"},
+			want:  []string{"fy"},
+			count: 1,
+		},
+		{
+			name:  "Ignore case",
+			cmd:   "s/RUBY/pearl/gi",
+			in:    []string{"Ruby ruby"},
+			want:  []string{"pearl pearl"},
+			count: 2,
+		},
+		{
+			name:  "Anchored pattern",
+			cmd:   "%s/^a/b/g",
+			in:    []string{"aaa", "bab"},
+			want:  []string{"baa", "bab"},
+			count: 1,
+		},
+		{
+			name:  "No match",
+			cmd:   "%s/z/y/g",
+			in:    []string{"aaa"},
+			want:  []string{"aaa"},
+			count: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := parseSubstitute(tt.cmd, exContext{lastLine: len(tt.in) - 1})
+			if err != nil {
+				t.Fatalf("parseSubstitute(%q) error = %v", tt.cmd, err)
+			}
+			lines := append([]string{}, tt.in...)
+			count := sub.Apply(lines)
+			if !reflect.DeepEqual(lines, tt.want) || count != tt.count {
+				t.Errorf("Apply() = %q, %d; want %q, %d", lines, count, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestSubstituteConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		in      []string
+		answers string
+		want    []string
+		made    int
+	}{
+		{
+			name:    "Alternate answers",
+			cmd:     "%s/one/two/gc",
+			in:      []string{"one one one one", "one one"},
+			answers: "nynyny",
+			want:    []string{"one two one two", "one two"},
+			made:    3,
+		},
+		{
+			name:    "Without g only the first match in each line is offered",
+			cmd:     "%s/a/b/c",
+			in:      []string{"aa", "aa", "aa"},
+			answers: "yny",
+			want:    []string{"ba", "aa", "ba"},
+			made:    2,
+		},
+		{
+			name:    "All",
+			cmd:     "%s/a/b/gc",
+			in:      []string{"aa", "aa"},
+			answers: "na",
+			want:    []string{"ab", "bb"},
+			made:    3,
+		},
+		{
+			name:    "Last",
+			cmd:     "%s/a/b/gc",
+			in:      []string{"aa", "aa"},
+			answers: "nl",
+			want:    []string{"ab", "aa"},
+			made:    1,
+		},
+		{
+			name:    "Quit",
+			cmd:     "%s/a/b/gc",
+			in:      []string{"aa", "aa"},
+			answers: "yq",
+			want:    []string{"ba", "aa"},
+			made:    1,
+		},
+		{
+			name:    "Replacement containing the pattern",
+			cmd:     "s/a/aa/gc",
+			in:      []string{"aba"},
+			answers: "yy",
+			want:    []string{"aabaa"},
+			made:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := parseSubstitute(tt.cmd, exContext{lastLine: len(tt.in) - 1})
+			if err != nil {
+				t.Fatalf("parseSubstitute(%q) error = %v", tt.cmd, err)
+			}
+			lines := append([]string{}, tt.in...)
+			c := newSubstituteConfirm(sub, lines)
+			for _, answer := range tt.answers {
+				if c.Done() {
+					t.Fatalf("confirmation finished before answer %q", answer)
+				}
+				c.Answer(answer, lines)
+			}
+			if !c.Done() {
+				t.Errorf("confirmation not finished after answers %q", tt.answers)
+			}
+			if !reflect.DeepEqual(lines, tt.want) || c.made != tt.made {
+				t.Errorf("lines = %q, made %d; want %q, %d", lines, c.made, tt.want, tt.made)
+			}
+		})
+	}
+}
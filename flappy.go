@@ -2,64 +2,211 @@ package main
 
 import (
 	"image/color"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+/*
+ * LevelFlappy swims the pufferfish past the pipes, holding j to swim down and k to swim up. The
+ * pipes scroll faster and their gaps shrink as the fish gets past them, and some pipes move up
+ * and down. Bubbles between the pipes are collected by putting the fish exactly on them, which
+ * takes a count, 3j swims three lanes down. In gravity mode, played as LevelIdFlappyGravity, the
+ * fish sinks and k flaps it up.
+ */
+
 const (
+	bubblePoints = 20 // for each bubble collected
+	bubbleRadius = 12
 	fishHeight   = 60
 	fishRadius   = (fishHeight / 2) - 5
 	fishScale    = 1.0
-	fishSpeed    = 3.0
+	fishSpeed    = 3.0 // pixels a tick
 	fishWidth    = 60
 	fishX        = 150
-	gapHeight    = 100
+	fishFrame    = 80          // width and height of the frames of the fish
+	flapImpulse  = 5.5         // upward speed of a flap in gravity mode
+	hitDuration  = time.Second // the fish shows it hit a pipe
+	laneHeight   = 40          // distance swum by a counted j or k
 	lastPipe     = 7
+	maxSinkSpeed = 8
+	pipeDrift    = 60 // pixels a moving pipe moves up and down from its center
+	pipeSpacing  = 5 * 60
 	pipeWidth    = 60
-	pipeInterval = 5 * 60
+	pipePoints   = 10 // for each pipe swum past
+	sinkRate     = 0.25
 )
 
+// numLanes is the number of lanes the fish can be put in with a count
+var numLanes = (screenHeight-fishHeight)/laneHeight + 1
+
+var flappyCommands = []commandSpec{
+	{keys: "j"},
+	{keys: "k"},
+}
+
 var (
 	colorPipe     = mediumCoal
 	colorPastPipe = darkGreen
 	seaColor      = mediumAluminium
 )
 
+// pufferfishAnimations is the animations of the pufferfish sprite sheet
+var pufferfishAnimations = map[string]AnimationSpec{
+	"idle":      {row: 0, frames: 4, frameTime: 200 * time.Millisecond, loop: true},
+	"swim up":   {row: 1, frames: 2, frameTime: 170 * time.Millisecond, loop: true},
+	"swim down": {row: 2, frames: 2, frameTime: 170 * time.Millisecond, loop: true},
+	"hit":       {row: 3, frames: 2, frameTime: 100 * time.Millisecond, loop: true},
+	"puff":      {row: 4, frames: 4, frameTime: 130 * time.Millisecond},
+}
+
+// flappyLevelConfig is the difficulty of a flappy level
+type flappyLevelConfig struct {
+	scrollSpeed  float32 // pixels the pipes move each tick at the start
+	speedUp      float32 // added to the scroll speed for each pipe passed
+	maxSpeed     float32
+	startGap     float32 // height of the gap in the first pipe
+	minGap       float32
+	gapShrink    float32 // taken off the gap of each pipe after the first
+	movingChance float64 // chance of a pipe moving up and down
+	bubbleChance float64 // chance of a bubble between two pipes
+	gravity      bool    // the fish sinks and k flaps it up, there are no bubbles
+}
+
+var flappyLevels = map[LevelID]flappyLevelConfig{
+	LevelIdFlappy: {
+		scrollSpeed:  1,
+		speedUp:      0.15,
+		maxSpeed:     2,
+		startGap:     160,
+		minGap:       100,
+		gapShrink:    8,
+		movingChance: 0.3,
+		bubbleChance: 0.5,
+	},
+	LevelIdFlappyGravity: {
+		scrollSpeed:  1,
+		speedUp:      0.1,
+		maxSpeed:     1.8,
+		startGap:     200,
+		minGap:       130,
+		gapShrink:    8,
+		movingChance: 0.2,
+		gravity:      true,
+	},
+}
+
 type LevelFlappy struct {
-	numPipesPast  int
-	fishImage     *ebiten.Image
-	fishY         float32
-	pipes         []*Pipe
-	startingFrame int
+	bubbles      []Bubble
+	config       flappyLevelConfig
+	fish         *Animation
+	hitUntil     time.Duration // game time the fish stops showing it hit a pipe
+	fishVY       float32       // speed of the fish in gravity mode, positive is down
+	fishY        float32
+	holdLocked   bool // after a counted move, j and k must be let go before holding them swims
+	level        LevelID
+	numPipes     int
+	particles    ParticleSystem
+	numPipesPast int
+	pipes        []*Pipe
+	scoreboard   Scoreboard
+	typed        string // the characters of a counted move being typed
 }
 
 type Pipe struct {
-	color         color.RGBA
-	completed     bool
-	gapY          float32
-	startingFrame int
-	x             float32
+	baseY     float32 // top of the gap at the center of a moving pipe
+	color     color.RGBA
+	completed bool
+	drift     float32 // pixels the pipe moves up and down, 0 for a still pipe
+	gap       float32
+	gapY      float32
+	start     time.Duration // game time the pipe was added, a moving pipe moves from then
+	x         float32
+}
+
+// Bubble is collected by the fish for points
+type Bubble struct {
+	x, y float32
 }
 
-func isCircleTouchingRect(circleX, circleY, circleRadius, rectLeft, rectTop, rectWidth, rectHeight float32) bool {
-	// Check if the circle's center is inside the rectangle
-	if circleX+circleRadius >= rectLeft && circleX-circleRadius <= rectLeft+rectWidth &&
-		circleY+circleRadius >= rectTop && circleY-circleRadius <= rectTop+rectHeight {
-		return true
+// speed returns the scroll speed after passed pipes
+func (c flappyLevelConfig) speed(passed int) float32 {
+	return min(c.scrollSpeed+c.speedUp*float32(passed), c.maxSpeed)
+}
+
+// gap returns the height of the gap in the pipe numbered n from 0
+func (c flappyLevelConfig) gap(n int) float32 {
+	return max(c.startGap-c.gapShrink*float32(n), c.minGap)
+}
+
+// pipeRects returns the top and bottom of a pipe, above and below its gap
+func pipeRects(p *Pipe) (Rect, Rect) {
+	top := Rect{p.x, 0, pipeWidth, p.gapY}
+	bottom := Rect{p.x, p.gapY + p.gap, pipeWidth, screenHeight - p.gapY - p.gap}
+	return top, bottom
+}
+
+// laneY returns the center of a lane
+func laneY(lane int) float32 {
+	return fishHeight/2 + float32(lane*laneHeight)
+}
+
+// nearestLane returns the lane nearest to y
+func nearestLane(y float32) int {
+	return int(math.Round(float64((y - fishHeight/2) / laneHeight)))
+}
+
+// countedMove returns where a counted j or k puts the fish at y, count lanes down or up from
+// the nearest lane and no further than the first or last lane
+func countedMove(y float32, keys string, count int) float32 {
+	if keys == "k" {
+		count = -count
 	}
-	return false
+	return laneY(limitToRange(nearestLane(y)+count, 0, numLanes-1))
 }
 
-func (l *LevelFlappy) addPipe(frameCount int) {
+// sink moves the fish on by steps ticks in gravity mode, a flap sends it up and it sinks ever
+// faster otherwise
+func sink(y, vy float32, flap bool, steps float32) (float32, float32) {
+	if flap {
+		vy = -flapImpulse
+	} else {
+		vy = min(vy+sinkRate*steps, maxSinkSpeed)
+	}
+	y += vy * steps
+	if y < fishHeight/2 || y > screenHeight-fishHeight/2 {
+		vy = 0
+	}
+	return limitToRange(y, fishHeight/2, screenHeight-fishHeight/2), vy
+}
+
+func (l *LevelFlappy) addPipe(now time.Duration) {
 	if l.numPipesPast <= lastPipe {
 		p := new(Pipe)
-		p.startingFrame = frameCount
-		p.gapY = float32(rng.Intn(screenHeight-(fishHeight*2)) + fishHeight/2)
+		p.start = now
+		p.gap = l.config.gap(l.numPipes)
+		if rng.Float64() < l.config.movingChance {
+			p.drift = pipeDrift
+		}
+		// keep the gap on the screen as the pipe moves
+		low := fishHeight/2 + p.drift
+		high := screenHeight - fishHeight/2 - p.gap - p.drift
+		p.baseY = low + rng.Float32()*(high-low)
+		p.gapY = p.baseY
 		p.x = screenWidth
 		l.pipes = append(l.pipes, p)
 		p.color = colorPipe
 		p.completed = false
+		l.numPipes++
+
+		if !l.config.gravity && rng.Float64() < l.config.bubbleChance {
+			// halfway to the next pipe
+			l.bubbles = append(l.bubbles, Bubble{x: p.x + pipeWidth/2 + pipeSpacing/2, y: laneY(rng.Intn(numLanes))})
+		}
 	}
 }
 
@@ -67,77 +214,191 @@ func (l *LevelFlappy) gameIsWon() bool {
 	return l.numPipesPast > lastPipe
 }
 
-func (l *LevelFlappy) checkPipeCollisions() {
+func (l *LevelFlappy) checkPipeCollisions(now time.Duration) {
 	for _, p := range l.pipes {
-		if isCircleTouchingRect(fishX, l.fishY, fishRadius, p.x, 0, pipeWidth, p.gapY) ||
-			isCircleTouchingRect(fishX, l.fishY, fishRadius, p.x, p.gapY+gapHeight, pipeWidth, screenHeight-p.gapY+gapHeight) {
+		top, bottom := pipeRects(p)
+		_, hitTop := touchCircle(fishX, l.fishY, fishRadius, top)
+		_, hitBottom := touchCircle(fishX, l.fishY, fishRadius, bottom)
+		if hitTop || hitBottom {
 			if p.color != darkScarletRed {
+				// a pipe that is hit does not count as passed
 				p.color = darkScarletRed
-				l.numPipesPast = 0
+				l.hitUntil = now + hitDuration
+				l.particles.Emit("crash", fishX, float64(l.fishY), nil)
+				l.scoreboard.LoseLife()
 				PlaySound(failOgg)
 			}
 		}
 	}
 }
 
-func (l *LevelFlappy) Draw(screen *ebiten.Image, frameCount int) {
+// collectBubbles collects the bubbles the fish is exactly on
+func (l *LevelFlappy) collectBubbles(now time.Duration) {
+	bubbles := l.bubbles[:0]
+	for _, b := range l.bubbles {
+		if math.Abs(float64(b.y-l.fishY)) < 1 && math.Abs(float64(b.x-fishX)) < fishRadius+bubbleRadius {
+			l.scoreboard.Add(bubblePoints, b.x, b.y-fishHeight, now)
+			l.fish.Restart("puff", now)
+			PlaySound(tripleOgg)
+		} else if b.x > -bubbleRadius {
+			bubbles = append(bubbles, b)
+		}
+	}
+	l.bubbles = bubbles
+}
+
+func (l *LevelFlappy) Draw(screen *ebiten.Image, now time.Duration) {
 	// Draw background
 	screen.Fill(seaColor)
 
-	// top pipe
+	// top and bottom of each pipe
 	for _, p := range l.pipes {
-		vector.DrawFilledRect(screen, p.x, 0, pipeWidth, p.gapY, p.color, false)
-		vector.DrawFilledRect(screen, p.x, p.gapY+gapHeight, pipeWidth, screenHeight-p.gapY+gapHeight, p.color, false)
+		top, bottom := pipeRects(p)
+		vector.DrawFilledRect(screen, top.left, top.top, top.width, top.height, p.color, false)
+		vector.DrawFilledRect(screen, bottom.left, bottom.top, bottom.width, bottom.height, p.color, false)
+	}
+
+	// bubbles show the count that swims the fish onto them
+	for _, b := range l.bubbles {
+		vector.StrokeCircle(screen, b.x, b.y, bubbleRadius, 2, lightAluminium, false)
+		if lanes := nearestLane(b.y) - nearestLane(l.fishY); lanes != 0 {
+			drawBufferString(screen, strconv.Itoa(abs(lanes)), int(b.x)+bubbleRadius+4, int(b.y)-bufferLineHeight/2, 0, 0, darkCoal)
+		}
 	}
 
 	// Draw fish
 	//vector.DrawFilledRect(screen, fishX-fishWidth/2, l.fishY-fishHeight/2, fishHeight, fishWidth, l.fishColor, false)
+	width, height := l.fish.Size()
 	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-float64(width)/2, -float64(height)/2)
 	op.GeoM.Scale(fishScale, fishScale)
-	op.GeoM.Translate(fishX-fishWidth/2, float64(l.fishY)-fishHeight/2)
-	screen.DrawImage(l.fishImage, op)
+	op.GeoM.Translate(fishX, float64(l.fishY))
+	screen.DrawImage(l.fish.Image(now), op)
+	l.particles.Draw(screen)
+
+	l.scoreboard.Draw(screen, now, darkCoal)
 }
 
 func (l *LevelFlappy) Initialize(id LevelID) {
-	l.fishY = screenHeight / 2
-	l.startingFrame = 0
+	l.level = id
+	l.config = flappyLevels[id]
+	l.scoreboard = NewScoreboard(id)
+	l.fishY = laneY(numLanes / 2)
+	l.fishVY = 0
+	l.holdLocked = false
+	l.typed = ""
+	l.numPipes = 0
 	l.numPipesPast = 0
-	if l.fishImage == nil {
-		l.fishImage = loadImage("resources/pufferfish80.png")
+	l.pipes = nil
+	l.bubbles = nil
+	l.hitUntil = 0
+	l.particles.Clear()
+	if l.fish == nil {
+		sheet := loadSpriteSheet("resources/pufferfish_sheet.png", fishFrame, fishFrame, pufferfishAnimations)
+		l.fish = NewAnimation(sheet, "idle", 0)
+	}
+	l.fish.Restart("idle", 0)
+}
+
+// handleCountedMoves puts the fish in a lane when a count and j or k is typed
+func (l *LevelFlappy) handleCountedMoves() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		l.typed = ""
+	}
+	for _, ch := range globalChars {
+		if l.typed == "" && !startsCommand(ch, flappyCommands) {
+			continue
+		}
+		l.typed += string(ch)
+		cmd, result := parseCommand(l.typed, flappyCommands)
+		switch result {
+		case commandIncomplete:
+			continue
+		case commandInvalid:
+			l.typed = ""
+			continue
+		}
+		l.typed = ""
+		if cmd.count > 0 {
+			l.fishY = countedMove(l.fishY, cmd.keys, cmd.count)
+			l.holdLocked = true
+		}
 	}
 }
 
-func (l *LevelFlappy) updateFish() {
+func (l *LevelFlappy) updateFish(step time.Duration) {
+	steps := float32(ticks(step))
+	if l.config.gravity {
+		l.fishY, l.fishVY = sink(l.fishY, l.fishVY, inpututil.IsKeyJustPressed(ebiten.KeyK), steps)
+		return
+	}
+	l.handleCountedMoves()
+
 	// Update vertical position based on keyboard input
 	heldDown := ebiten.IsKeyPressed(ebiten.KeyJ)
 	heldUp := ebiten.IsKeyPressed(ebiten.KeyK)
-	if heldDown || heldUp {
+	if !heldDown && !heldUp {
+		l.holdLocked = false
+	}
+	if (heldDown || heldUp) && !l.holdLocked && l.typed == "" {
 		clearKeystrokes()
 		if heldDown && !heldUp {
-			l.fishY += fishSpeed
+			l.fishY += fishSpeed * steps
 		} else if !heldDown && heldUp {
-			l.fishY -= fishSpeed
+			l.fishY -= fishSpeed * steps
 		}
 		l.fishY = limitToRange(l.fishY, fishHeight/2, screenHeight-fishHeight/2)
 	}
 }
 
-func (l *LevelFlappy) updatePipes(frameCount int) {
-	if l.startingFrame == 0 {
-		l.startingFrame = frameCount
-		l.addPipe(frameCount)
-	} else if (frameCount-l.startingFrame+pipeInterval)%pipeInterval == 0 {
-		l.addPipe(frameCount)
+// animateFish plays the animation for what the fish is doing, it was at lastY before it moved.
+// A puff plays to the end.
+func (l *LevelFlappy) animateFish(now time.Duration, lastY float32) {
+	switch {
+	case now < l.hitUntil:
+		l.fish.Play("hit", now)
+	case l.fish.Playing() == "puff" && !l.fish.Done(now):
+	case l.fishY < lastY:
+		l.fish.Play("swim up", now)
+	case l.fishY > lastY:
+		l.fish.Play("swim down", now)
+	default:
+		l.fish.Play("idle", now)
+	}
+}
+
+// Failed returns true when the last life is lost
+func (l *LevelFlappy) Failed() (string, bool) {
+	return l.scoreboard.Failed()
+}
+
+// Score returns the points for the pipes passed and bubbles collected
+func (l *LevelFlappy) Score() int {
+	return l.scoreboard.score
+}
+
+func (l *LevelFlappy) updatePipes(now, step time.Duration) {
+	if len(l.pipes) == 0 || l.pipes[len(l.pipes)-1].x <= screenWidth-pipeSpacing {
+		l.addPipe(now)
 	}
 
-	// move pipes forward
+	// move pipes and bubbles forward, faster as more pipes are passed
+	speed := l.config.speed(l.numPipesPast) * float32(ticks(step))
 	for _, p := range l.pipes {
-		p.x -= 1
+		p.x -= speed
+		if p.drift > 0 {
+			phase := (now - p.start).Seconds()
+			p.gapY = p.baseY + p.drift*float32(math.Sin(phase))
+		}
 		if p.x < fishX && p.color == colorPipe {
 			l.numPipesPast += 1
 			p.color = colorPastPipe
+			l.scoreboard.Add(pipePoints, fishX, l.fishY-fishHeight, now)
 		}
 	}
+	for i := range l.bubbles {
+		l.bubbles[i].x -= speed
+	}
 
 	// remove pipe that are off screen
 	var newSlice []*Pipe
@@ -149,7 +410,7 @@ func (l *LevelFlappy) updatePipes(frameCount int) {
 	l.pipes = newSlice
 }
 
-func (l *LevelFlappy) Update(frameCount int) (bool, error) {
+func (l *LevelFlappy) Update(clock Clock) (bool, error) {
 	if isCheatKeyPressed() {
 		return true, nil
 	}
@@ -157,10 +418,20 @@ func (l *LevelFlappy) Update(frameCount int) (bool, error) {
 	if l.gameIsWon() {
 		return true, nil
 	}
+	now, step := clock.Now(), clock.Step()
+	// the burst of the last hit plays out after the game is over
+	l.particles.Update(step)
+	if l.scoreboard.GameOver() {
+		return false, nil
+	}
 
-	l.updateFish()
-	l.updatePipes(frameCount)
-	l.checkPipeCollisions()
+	l.scoreboard.Update(now)
+	fishY := l.fishY
+	l.updateFish(step)
+	l.updatePipes(now, step)
+	l.checkPipeCollisions(now)
+	l.collectBubbles(now)
+	l.animateFish(now, fishY)
 
 	return false, nil
 }
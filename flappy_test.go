@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPipeRects(t *testing.T) {
+	tests := []struct {
+		name                string
+		pipe                Pipe
+		wantTop, wantBottom Rect
+	}{
+		{"Gap in the middle", Pipe{x: 300, gapY: 250, gap: 100}, Rect{300, 0, pipeWidth, 250}, Rect{300, 350, pipeWidth, 250}},
+		{"Gap at the top", Pipe{x: 0, gapY: 0, gap: 160}, Rect{0, 0, pipeWidth, 0}, Rect{0, 160, pipeWidth, 440}},
+		{"Gap at the bottom", Pipe{x: 10, gapY: 500, gap: 100}, Rect{10, 0, pipeWidth, 500}, Rect{10, 600, pipeWidth, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top, bottom := pipeRects(&tt.pipe)
+			if top != tt.wantTop || bottom != tt.wantBottom {
+				t.Errorf("pipeRects() = %v, %v, want %v, %v", top, bottom, tt.wantTop, tt.wantBottom)
+			}
+			if bottom.bottom() != screenHeight {
+				t.Errorf("bottom pipe ends at %v, want the bottom of the screen", bottom.bottom())
+			}
+		})
+	}
+}
+
+func TestPipeCollisions(t *testing.T) {
+	pipe := Pipe{x: fishX - pipeWidth/2, gapY: 200, gap: 100}
+	tests := []struct {
+		name  string
+		fishY float32
+		want  bool
+	}{
+		{"Centered in the gap", 250, false},
+		{"Touching the top", 200 + fishRadius - 1, true},
+		{"Touching the bottom", 300 - fishRadius + 1, true},
+		{"Clear of the bottom", 300 - fishRadius - 1, false},
+		{"Well below the gap", 550, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top, bottom := pipeRects(&pipe)
+			_, hitTop := touchCircle(fishX, tt.fishY, fishRadius, top)
+			_, hitBottom := touchCircle(fishX, tt.fishY, fishRadius, bottom)
+			if got := hitTop || hitBottom; got != tt.want {
+				t.Errorf("hit = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlappyDifficulty(t *testing.T) {
+	config := flappyLevelConfig{scrollSpeed: 1, speedUp: 0.25, maxSpeed: 2, startGap: 160, minGap: 100, gapShrink: 20}
+	tests := []struct {
+		n         int
+		wantSpeed float32
+		wantGap   float32
+	}{
+		{0, 1, 160},
+		{2, 1.5, 120},
+		{3, 1.75, 100},
+		{10, 2, 100},
+	}
+	for _, tt := range tests {
+		if got := config.speed(tt.n); got != tt.wantSpeed {
+			t.Errorf("speed(%d) = %v, want %v", tt.n, got, tt.wantSpeed)
+		}
+		if got := config.gap(tt.n); got != tt.wantGap {
+			t.Errorf("gap(%d) = %v, want %v", tt.n, got, tt.wantGap)
+		}
+	}
+}
+
+func TestCountedMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		y     float32
+		keys  string
+		count int
+		want  float32
+	}{
+		{"Down from a lane", laneY(3), "j", 2, laneY(5)},
+		{"Up from a lane", laneY(3), "k", 2, laneY(1)},
+		{"From between lanes", laneY(3) + 5, "j", 1, laneY(4)},
+		{"Stops at the top", laneY(1), "k", 5, laneY(0)},
+		{"Stops at the bottom", laneY(numLanes - 2), "j", 9, laneY(numLanes - 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countedMove(tt.y, tt.keys, tt.count); got != tt.want {
+				t.Errorf("countedMove() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSink(t *testing.T) {
+	tests := []struct {
+		name          string
+		y, vy         float32
+		flap          bool
+		wantY, wantVY float32
+	}{
+		{"Sinks", 300, 0, false, 300 + sinkRate, sinkRate},
+		{"Flaps", 300, 4, true, 300 - flapImpulse, -flapImpulse},
+		{"Falls no faster than the limit", 300, maxSinkSpeed, false, 300 + maxSinkSpeed, maxSinkSpeed},
+		{"Rests on the bottom", screenHeight - fishHeight/2, 3, false, screenHeight - fishHeight/2, 0},
+		{"Stops at the top", fishHeight / 2, 0, true, fishHeight / 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y, vy := sink(tt.y, tt.vy, tt.flap, 1)
+			if y != tt.wantY || vy != tt.wantVY {
+				t.Errorf("sink() = %v, %v, want %v, %v", y, vy, tt.wantY, tt.wantVY)
+			}
+		})
+	}
+}
+
+func TestFlappyGravityLevelSinks(t *testing.T) {
+	l := &LevelFlappy{config: flappyLevels[LevelIdFlappyGravity], fishY: laneY(numLanes / 2)}
+	start := l.fishY
+	for range 10 {
+		l.updateFish(tick)
+	}
+	if l.fishY <= start {
+		t.Errorf("fishY = %v after 10 ticks, want below %v", l.fishY, start)
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"log"
+
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
 )
 
 const (
@@ -77,3 +80,16 @@ func loadFont(path string, size float64) (font.Face, error) {
 		Hinting: font.HintingFull,
 	}), nil
 }
+
+// newMonoFace creates a monospaced font face for drawing text buffers in the editing levels.
+func newMonoFace(size float64) font.Face {
+	ttfFont, err := truetype.Parse(gomono.TTF)
+	if err != nil {
+		log.Fatal("Error Parsing Font", err)
+	}
+	return truetype.NewFace(ttfFont, &truetype.Options{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
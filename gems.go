@@ -1,8 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -10,461 +14,469 @@ import (
 )
 
 const (
-	blinkInverval = 60 / 3
-	emptyGem      = -1
-	dropDuration  = 60
-	gemCellSize   = 50
-	gemScale      = float64(gemCellSize-4) / float64(gemWidth)
-	gemWidth      = 100
-	numGemRows    = 11
-	swapDuration  = 40
+	blinkInterval  = time.Second / 3
+	chainDuration  = time.Second // the chain multiplier is shown for
+	emptyGem       = -1
+	dropDuration   = time.Second
+	popDuration    = 330 * time.Millisecond // a gem promoted to a special gem takes to pop
+	vanishDuration = 330 * time.Millisecond // a removed gem takes to vanish
+	gemCellSize    = 50
+	gemLegendLeft  = 20
+	gemLegendTop   = 100
+	gemScale       = float64(gemCellSize-4) / float64(gemWidth)
+	specialScale   = 0.5 // size of the overlay on a special gem relative to the gem
+	gemWidth       = 100
+	hintKey        = ebiten.KeyF1
+	numGemRows     = 11
+	swapDuration   = 670 * time.Millisecond
 )
 
 var (
 	lightGold   = color.RGBA{0xff, 0xff, 0x80, 0xff}
 	redCursor   = color.RGBA{0xfc, 0x10, 0x10, 0x40}
 	whiteCursor = color.RGBA{0xac, 0xac, 0xac, 0x40}
+	gemColors   = []color.RGBA{ // the color of each gem image, for the sparks of a match
+		{0xef, 0x94, 0xf3, 0xff},
+		{0x8e, 0xee, 0x9f, 0xff},
+		{0x7f, 0xf1, 0xf0, 0xff},
+		{0x89, 0x93, 0xec, 0xff},
+		{0xf0, 0x8d, 0x8d, 0xff},
+		{0xba, 0x97, 0xf4, 0xff},
+	}
 )
 
-var numGemColumns int
-
-type GemMover struct {
-	startFrame int
-	endFrame   int
-	startGrid  Coord // grid coordinates
-	endCoord   Coord // grid coordinates
+// gemsMotionCommands move the cursor in normal mode, a count moves it that many squares
+var gemsMotionCommands = []commandSpec{
+	{keys: "h"}, {keys: "j"}, {keys: "k"}, {keys: "l"},
 }
-type LevelGems struct {
-	cursorGem   Coord
-	gemGrid     Grid[Square]
-	gemImages   []*ebiten.Image
-	level       LevelID
-	viMode      VIMode
-	numGems     int
-	swapGem     Coord
-	triplesMask Grid[bool]
-}
-
-type Square struct {
-	gem    int
-	mover  *GemMover
-	coords Coord // position in the grid
-}
-
-func applyMover(mover *GemMover, op *ebiten.DrawImageOptions, frameCount int) {
-	completionRatio := 1 - float64(mover.endFrame-frameCount)/float64(mover.endFrame-mover.startFrame)
-	startPosition := squareToScreenPoint(mover.startGrid)
-	endPosition := squareToScreenPoint(mover.endCoord)
-	op.GeoM.Translate(
-		float64(startPosition.x)+(completionRatio*float64(endPosition.x-startPosition.x)),
-		float64(startPosition.y)+(completionRatio*float64(endPosition.y-startPosition.y)))
-}
-
-// fillFromBelow() generates new gems to fill in empty squares at the bottom of the gem grid
-func fillFromBelow(gemGrid *Grid[Square], numGems, frameCount int, addMover bool) {
-	// fill empties at the bottom of the gemGrid with newly generated gems
-	for x := range numGemColumns {
-		for y := range numGemRows {
-			if gemGrid.Get(Coord{x, y}).gem == emptyGem {
-				sqPtr := gemGrid.GetPtr(Coord{x, y})
-				sqPtr.gem = rng.Intn(numGems)
-				if addMover {
-					sqPtr.addMover(frameCount, dropDuration,
-						Coord{sqPtr.coords.x, numGemRows + 1},
-						Coord{x, y})
-				}
-			}
-		}
-	}
+
+var gemsEditCommands = []commandSpec{
+	{keys: "r", argument: true},
+	{keys: "R"},
+	{keys: "x"},
+	{keys: "~"},
 }
 
-// highLow() returns the highest and lowest points in that order where highest is closest to the top left of the screen
-// disregard a point if it has negative values
-func highLow(p1, p2 Coord) (Coord, Coord) {
-	if p2.x < 0 || p2.y < 0 {
-		return p1, p2
-	}
-	if p1.x < 0 || p1.y < 0 {
-		return p2, p1
-	}
-	if p1.y == p2.y {
-		if p1.x >= p2.x {
-			return p2, p1
-		} else {
-			return p1, p2
-		}
-	} else {
-		if p1.y > p2.y {
-			return p2, p1
-		} else {
-			return p1, p2
-		}
-	}
+var gemsRowCommands = []commandSpec{
+	{keys: "J"},
+	{keys: ">>"},
+	{keys: "<<"},
 }
 
-// moveUpFromBelow() moves gems from below to fill in empty squares
-func moveUpFromBelow(gemGrid *Grid[Square], frameCount int, addMover bool) {
-	// for each column
-	for x := range numGemColumns {
-		// find empty square and move squares from below to fill
-		for y := 0; y < numGemRows; y++ {
-			pt := Coord{x, y}
-			if gemGrid.Get(pt).gem == emptyGem {
-				below := findSquareBelow(gemGrid, pt)
-				if below.y >= 0 {
-					sqPtr := gemGrid.GetPtr(pt)
-					sqPtr.gem = gemGrid.Get(below).gem
-					sqPtr = gemGrid.GetPtr(below)
-					sqPtr.gem = emptyGem
-					if addMover {
-						sqPtr = gemGrid.GetPtr(pt)
-						sqPtr.addMover(frameCount, dropDuration, below, pt)
-					}
-				}
-			}
-		}
-	}
+// gemsLevelConfig is the size of the board, the commands and the budget of a gems level
+type gemsLevelConfig struct {
+	numGems      int
+	columns      int
+	moves        []GemMoveKind
+	joinOverflow joinOverflow
+	maxMoves     int           // moves allowed to win the level, 0 for no limit
+	timeLimit    time.Duration // allowed to win the level, 0 for no limit
 }
 
-// newGridOfSquares() creates a grid of squares
-func newGridOfSquares(width, height int) Grid[Square] {
-	r := make([][]Square, height)
-	for i := range r {
-		r[i] = make([]Square, width)
-		for j := range r[i] {
-			r[i][j] = Square{coords: Coord{j, i}}
-		}
-	}
-	return r
+var gemsLevels = map[LevelID]gemsLevelConfig{
+	LevelIdGemsDD: {
+		numGems: 4,
+		columns: 8,
+		moves:   []GemMoveKind{MoveDeleteRows},
+	},
+	LevelIdGemsVM: {
+		numGems: 5,
+		columns: 5,
+		moves:   []GemMoveKind{MoveDeleteSelection},
+	},
+	LevelIdGemsReplace: {
+		numGems:  5,
+		columns:  6,
+		moves:    []GemMoveKind{MoveDeleteRows, MoveDeleteGem, MoveReplaceGem},
+		maxMoves: 60,
+	},
+	LevelIdGemsJoin: {
+		numGems:      5,
+		columns:      6,
+		moves:        []GemMoveKind{MoveDeleteRows, MoveJoinRows, MoveShiftRows},
+		joinOverflow: joinDiscard,
+		timeLimit:    4 * time.Minute,
+	},
+	LevelIdGemsEnd: {
+		numGems: 6,
+		columns: 10,
+		moves: []GemMoveKind{MoveDeleteRows, MoveDeleteSelection, MoveDeleteGem, MoveReplaceGem,
+			MoveJoinRows, MoveShiftRows},
+		joinOverflow: joinWrap,
+		maxMoves:     80,
+		timeLimit:    10 * time.Minute,
+	},
 }
 
-func setGem(grid *Grid[Square], pt Coord, gem int) {
-	sq := grid.Get(pt)
-	sq.gem = gem
-	grid.Set(pt, sq)
+// GemAnimation is a gem moving, scaling, turning or fading
+type GemAnimation struct {
+	transform Transform
+	tween     Tweener
 }
 
-// Set all gems in a selection to a value
-func setSelection(gemGrid *Grid[Square], p1, p2 Coord, gem int) {
-	cursorStart, cursorEnd := highLow(p1, p2)
-	startX := cursorStart.x
-	for y := cursorStart.y; y <= cursorEnd.y; y++ {
-		for x := startX; x < numGemColumns; x++ {
-			setGem(gemGrid, Coord{x, y}, gem)
-			if x == cursorEnd.x && y == cursorEnd.y {
-				break
-			}
-			// start next line at left edge
-			startX = 0
-		}
-	}
+// VanishingGem is a gem removed from the board, drawn as it vanishes
+type VanishingGem struct {
+	GemAnimation
+	gem int
+}
+type LevelGems struct {
+	board         *Board
+	cascade       []CascadeStep // steps of the cascade still to be shown
+	chain         int           // multiplier of the last cascade step
+	chainShown    time.Duration // game time the last cascade step was shown
+	cursorGem     Coord
+	failReason    string // why the level was lost, empty while it can still be won
+	gemImages     []*ebiten.Image
+	specialImages map[GemKind]*ebiten.Image
+	hint          *GemMove // the move highlighted after the hint key, nil if none is shown
+	level         LevelID
+	maxMoves      int // 0 for no limit
+	movesMade     int
+	moving        Grid[*GemAnimation] // animation of the gem in each square, nil when it is still
+	viMode        VIMode
+	numGems       int
+	played        time.Duration // game time spent on the level
+	particles     ParticleSystem
+	replaceBackup *Board // the board before replace mode, restored if the replace fails
+	score         int
+	shown         *Board // the gems drawn, behind the board while a cascade is shown
+	solver        Solver
+	swapGem       Coord
+	timeLimit     time.Duration // 0 for no limit
+	triplesMask   Grid[bool]
+	typed         string // characters of a partly typed edit command
+	vanishing     []*VanishingGem
+	viewport      Viewport
 }
 
-// convert the x,y of the square into screen coordinates
-func squareToScreenPoint(squareXY Coord) Coord {
-	// get leftmost x
-	widthOfGrid := gemCellSize * numGemColumns
-	xMargin := (screenWidth - widthOfGrid) / 2
-	// get top y
-	heightOfGrid := gemCellSize * numGemRows
-	yMargin := (screenHeight - heightOfGrid) / 2
-	return Coord{
-		gemCellSize*squareXY.x + xMargin,
-		gemCellSize*squareXY.y + yMargin,
-	}
+// cellTransform returns the transform drawing a gem still in square p
+func (l *LevelGems) cellTransform(p Coord) Transform {
+	pos := l.viewport.CellToScreen(p)
+	return NewTransform(float64(pos.x), float64(pos.y))
 }
 
-func (l *LevelGems) Draw(screen *ebiten.Image, frameCount int) {
+func (l *LevelGems) Draw(screen *ebiten.Image, now time.Duration) {
 	screen.Fill(darkCoal)
 
 	// draw background of triples
-	l.gemGrid.ForEach(func(p Coord, s Square) {
-		if l.triplesMask.Get(p) {
-			s.drawBackground(screen, lightGold)
+	l.triplesMask.ForEach(func(p Coord, gold bool) {
+		if gold {
+			l.drawBackground(screen, p, lightGold)
 		}
 	})
 
-	l.drawSelection(screen, frameCount)
-	l.drawCursor(screen, frameCount)
+	l.drawSelection(screen)
+	l.drawHint(screen)
+	l.drawCursor(screen, now)
 
-	// draw cursor
-	cursorColors := [2]color.Color{redCursor, whiteCursor}
-	blink := frameCount / blinkInverval % 2
-
-	switch l.level {
-	case LevelIdGemsEnd:
-		fallthrough
-	case LevelIdGemsVM:
-		s := l.gemGrid.Get(l.cursorGem)
-		s.drawBackground(screen, cursorColors[blink])
-	case LevelIdGemsDD:
-		l.gemGrid.ForEach(func(p Coord, s Square) {
-			if p.y == l.cursorGem.y {
-				s.drawBackground(screen, cursorColors[blink])
-			}
-		})
-	}
 	// draw gems
-	l.gemGrid.ForEach(func(p Coord, s Square) {
-		if s.gem >= 0 {
-			s.drawGem(screen, l.gemImages[s.gem], frameCount)
-		} else {
+	for _, v := range l.vanishing {
+		l.drawGem(screen, v.transform, l.gemImages[v.gem], GemNormal)
+	}
+	l.shown.gems.ForEach(func(p Coord, gem int) {
+		if gem < 0 {
 			// shouldn't get here
-			s.drawBackground(screen, darkGreen)
+			l.drawBackground(screen, p, darkGreen)
+		} else if a := l.moving.Get(p); a != nil {
+			l.drawGem(screen, a.transform, l.gemImages[gem], l.shown.Kind(p))
+		} else {
+			l.drawGem(screen, l.cellTransform(p), l.gemImages[gem], l.shown.Kind(p))
 		}
 	})
+
+	l.particles.Draw(screen)
+
+	if l.allowsEdits() {
+		l.drawLegend(screen)
+	}
+	l.drawScore(screen, now)
+}
+
+func (l *LevelGems) drawBackground(screen *ebiten.Image, p Coord, color color.Color) {
+	pos := l.viewport.CellToScreen(p)
+	vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y), gemCellSize-4, gemCellSize-4, color, false)
 }
 
-func (l *LevelGems) drawCursor(screen *ebiten.Image, frameCount int) {
+func (l *LevelGems) drawCursor(screen *ebiten.Image, now time.Duration) {
 	// draw cursor
 	cursorColors := [2]color.Color{redCursor, whiteCursor}
-	blink := frameCount / blinkInverval % 2
+	blink := blinkPhase(now, blinkInterval)
 
 	switch l.level {
 	case LevelIdGemsEnd:
 		fallthrough
-	case LevelIdGemsVM:
-		s := l.gemGrid.Get(l.cursorGem)
-		s.drawBackground(screen, cursorColors[blink])
-	case LevelIdGemsDD:
+	case LevelIdGemsVM, LevelIdGemsReplace:
+		l.drawBackground(screen, l.cursorGem, cursorColors[blink])
+	case LevelIdGemsDD, LevelIdGemsJoin:
 		// highlight the entire row
-		l.gemGrid.ForEach(func(p Coord, s Square) {
-			if p.y == l.cursorGem.y {
-				s.drawBackground(screen, cursorColors[blink])
-			}
-		})
+		for x := range l.board.Columns() {
+			l.drawBackground(screen, Coord{x, l.cursorGem.y}, cursorColors[blink])
+		}
 	}
 }
-func (l *LevelGems) drawSelection(screen *ebiten.Image, frameCount int) {
-	if l.viMode == VisualMode {
-		cursorStart, cursorEnd := highLow(l.cursorGem, l.swapGem)
-		startX := cursorStart.x
-		for y := cursorStart.y; y <= cursorEnd.y; y++ {
-			for x := startX; x < numGemColumns; x++ {
-				s := l.gemGrid.Get(Coord{x, y})
-				s.drawBackground(screen, darkGreen)
-				if x == cursorEnd.x && y == cursorEnd.y {
-					break
-				}
-				// start next line at left edge
-				startX = 0
-			}
-		}
+
+func (l *LevelGems) drawGem(screen *ebiten.Image, t Transform, gemImage *ebiten.Image, kind GemKind) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(gemScale, gemScale)
+	t.Apply(op, gemCellSize-4, gemCellSize-4)
+	screen.DrawImage(gemImage, op)
+
+	if kind != GemNormal {
+		l.drawSpecial(screen, kind, op.GeoM)
 	}
 }
 
-// Delete all gems in a row. If it does nor result in a triple the delete will fail and restore to original state.
-func (l *LevelGems) deleteRows(numRows, frameCount int) bool {
-	// copy the gem grid
-	newGrid := l.gemGrid.Copy()
-
-	// remove the rows
-	for i := 0; i < numRows; i++ {
-		row := l.cursorGem.y
-		if row >= newGrid.NumRows() {
-			break
-		}
-		newGrid = newGrid.DeleteRow(row)
+// drawSpecial draws the overlay of a special gem, small in the middle of the gem drawn with geoM
+func (l *LevelGems) drawSpecial(screen *ebiten.Image, kind GemKind, geoM ebiten.GeoM) {
+	x, y := geoM.Apply(0, 0)
+	size := float64(gemCellSize - 4)
+	if kind == GemLineClear {
+		// a stripe across the gem shows it clears the row
+		vector.StrokeLine(screen, float32(x), float32(y+size/2), float32(x+size), float32(y+size/2), 4, lightGold, false)
 	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(gemScale*specialScale, gemScale*specialScale)
+	op.GeoM.Translate(x+size*(1-specialScale)/2, y+size*(1-specialScale)/2)
+	screen.DrawImage(l.specialImages[kind], op)
+}
 
-	// check if the swap will create a triple
-	makesATriple, _ := findTriples(newGrid)
+// drawHint highlights the squares of the hinted move and shows the keys for it
+func (l *LevelGems) drawHint(screen *ebiten.Image) {
+	if l.hint == nil {
+		return
+	}
+	for _, p := range l.hint.Cells(l.board) {
+		l.drawBackground(screen, p, mediumGreen)
+	}
+	drawBufferString(screen, "Hint: "+l.hint.Keys(), gemLegendLeft, screenHeight-2*bufferLineHeight-10, 0, 0, bufferText)
+}
 
-	if makesATriple {
-		// mark squares in deleted rows as EMPTY_GEM
-		for i := 0; i < numRows; i++ {
-			row := l.cursorGem.y + i
-			if row >= l.gemGrid.NumRows() {
-				break
+// drawLegend shows the number of each gem, used by r{n} and replace mode
+func (l *LevelGems) drawLegend(screen *ebiten.Image) {
+	for i, image := range l.gemImages {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(gemScale, gemScale)
+		op.GeoM.Translate(gemLegendLeft, float64(gemLegendTop+i*gemCellSize))
+		screen.DrawImage(image, op)
+		drawBufferString(screen, strconv.Itoa(i+1), gemLegendLeft+gemCellSize, gemLegendTop+i*gemCellSize+10, 0, 0, bufferText)
+	}
+	if l.viMode == ReplaceMode {
+		drawBufferString(screen, "-- REPLACE --", gemLegendLeft, screenHeight-bufferLineHeight-10, 0, 0, bufferText)
+	}
+}
+
+// drawScore shows the score and, after a chain, its multiplier
+func (l *LevelGems) drawScore(screen *ebiten.Image, now time.Duration) {
+	drawBufferString(screen, fmt.Sprintf("Score %d", l.score), gemLegendLeft, 20, 0, 0, bufferText)
+	if l.chain > 1 && now < l.chainShown+chainDuration {
+		drawBufferString(screen, fmt.Sprintf("Chain x%d", l.chain), gemLegendLeft, 20, 0, 1, lightGold)
+	}
+	if l.maxMoves > 0 || l.timeLimit > 0 {
+		drawBufferString(screen, l.Remaining(), gemLegendLeft, 20, 0, 2, bufferText)
+	}
+}
+
+func (l *LevelGems) drawSelection(screen *ebiten.Image) {
+	if l.viMode == VisualMode {
+		for _, p := range l.selection().Cells(l.board.Columns()) {
+			l.drawBackground(screen, p, darkGreen)
+		}
+	}
+}
+
+// animate turns the events from a change to the board into animations. Removed gems vanish
+// before the gems above them fall, matched and blasted gems throw out sparks, and a gem promoted
+// to a special gem pops.
+func (l *LevelGems) animate(events []BoardEvent) {
+	for _, e := range events {
+		switch e.kind {
+		case GemMatched, GemDeleted, GemBlasted:
+			l.moving.Set(e.to, nil)
+			if e.gem < 0 {
+				continue
 			}
-			for x := range l.gemGrid.NumColumns() {
-				p := Coord{x, row}
-				setGem(&l.gemGrid, p, emptyGem)
+			t := l.cellTransform(e.to)
+			l.vanishing = append(l.vanishing, &VanishingGem{
+				GemAnimation: GemAnimation{transform: t, tween: VanishTween(vanishDuration)},
+				gem:          e.gem,
+			})
+			if e.kind != GemDeleted {
+				center := float64(gemCellSize-4) / 2
+				l.particles.Emit("match", t.x+center, t.y+center, gemColors[e.gem%len(gemColors)])
 			}
 		}
-	} else {
-		PlaySound(failOgg)
-		// penalize player for invalid move
-		// mark row to be deleted as EMPTY_GEM
-		for x := range l.gemGrid.NumColumns() {
-			l.triplesMask.Set(Coord{x, l.cursorGem.y}, false)
+	}
+	// gems removed by the move, in these events or earlier ones, vanish before the rest fall
+	var delay time.Duration
+	if len(l.vanishing) > 0 {
+		delay = vanishDuration
+	}
+	for _, e := range events {
+		switch e.kind {
+		case GemFell, GemSpawned:
+			from, to := l.cellTransform(e.from), l.cellTransform(e.to)
+			l.moving.Set(e.to, &GemAnimation{
+				transform: from,
+				tween:     Sequence(Delay(delay), MoveTween(from.x, from.y, to.x, to.y, dropDuration, EaseInQuad)),
+			})
+		case GemPromoted:
+			t := l.cellTransform(e.to)
+			t.scale = 0
+			l.moving.Set(e.to, &GemAnimation{transform: t, tween: Sequence(Delay(delay), NewTween(ChannelScale, 0, 1, popDuration, EaseOutBack))})
 		}
-		return false
 	}
-	l.fillEmpties(frameCount, true)
-	return true
 }
 
-// Delete all gems selected in visual mode.
-// If it does nor result in a triple the delete will fail and restore to original state.
-func (l *LevelGems) deleteSelectionReplaceFromBelow(frameCount int) bool {
-
-	// copy the gem grid to test if removing the selected squares will result in a triple
-	newGrid := l.gemGrid.Copy()
-
-	// set all selected squares to EMPTY_GEM
-	setSelection(&newGrid, l.cursorGem, l.swapGem, emptyGem)
-	moveUpFromBelow(&newGrid, frameCount, false)
-
-	// check if the swap will create a triple
-	makesATriple, _ := findTriples(newGrid)
+// stepAnimations advances the animations of the gems by dt, returning true while any are playing
+func (l *LevelGems) stepAnimations(dt time.Duration) bool {
+	playing := false
+	l.moving.ForEach(func(p Coord, a *GemAnimation) {
+		if a == nil {
+			return
+		}
+		if a.tween.Step(&a.transform, dt) {
+			l.moving.Set(p, nil)
+		} else {
+			playing = true
+		}
+	})
+	vanishing := l.vanishing[:0]
+	for _, v := range l.vanishing {
+		if !v.tween.Step(&v.transform, dt) {
+			vanishing = append(vanishing, v)
+		}
+	}
+	l.vanishing = vanishing
+	return playing || len(l.vanishing) > 0
+}
 
-	if makesATriple {
-		// set all selected squares to EMPTY_GEM
-		setSelection(&l.gemGrid, l.cursorGem, l.swapGem, emptyGem)
-	} else {
+// makeMove makes the move if it results in a triple, otherwise the player is penalized. Only a
+// move that is made counts against the budget.
+func (l *LevelGems) makeMove(m GemMove) bool {
+	if l.outOfMoves() {
 		PlaySound(failOgg)
-		// penalize player for invalid move
-		// mark row to be deleted as EMPTY_GEM
-		for x := range l.gemGrid.NumColumns() {
-			l.triplesMask.Set(Coord{x, l.cursorGem.y}, false)
-		}
 		return false
 	}
-	l.fillEmpties(frameCount, true)
+	l.finishCascade()
+	if !m.IsValid(l.board) {
+		l.penalize()
+		return false
+	}
+	l.useMove()
+	events := m.Apply(l.board)
+	for _, e := range events {
+		if e.kind == GemBlasted {
+			l.score += gemScore
+		}
+	}
+	l.gildBlasted(events)
+	l.animate(events)
+	l.settle()
 	return true
 }
 
+// Delete all gems in a row. If it does nor result in a triple the delete will fail and restore to original state.
+func (l *LevelGems) deleteRows(numRows int) bool {
+	return l.makeMove(GemMove{kind: MoveDeleteRows, from: l.cursorGem, count: numRows})
+}
+
+// Delete all gems selected in visual mode.
+// If it does nor result in a triple the delete will fail and restore to original state.
+func (l *LevelGems) deleteSelectionReplaceFromBelow() bool {
+	return l.makeMove(GemMove{kind: MoveDeleteSelection, from: l.cursorGem, to: l.swapGem})
+}
+
 // Not used.
 // Delete all gems selected in visual mode.
 // If it does nor result in a triple the delete will fail and restore to original state.
 // This moves gems left to fill the empty space and wraps gems from the next row.
-func (l *LevelGems) deleteSelectionReplaceFromRight(frameCount int) bool {
-	// copy the gem grid to test if removing the selected squares will result in a triple
-	newGrid := l.gemGrid.Copy()
-
-	{ // using bracket notation to scope local variables
-
-		// set all selected squares to EMPTY_GEM
-		selectionStart, selectionEnd := highLow(l.cursorGem, l.swapGem)
-		dest := newGrid.IndexOf(selectionStart)
-		src := newGrid.IndexOf(selectionEnd) + 1
-
-		var gem int
-		// move squares past selection into the selection
-		for i := dest; i < newGrid.LastIndex(); i++ {
-			if src >= newGrid.LastIndex() {
-				gem = emptyGem
-			} else {
-				gem = newGrid.GetAtIndex(src).gem
-			}
-			setGem(&newGrid, newGrid.IndexToCoord(i), gem)
-			src++
-		}
-	}
-
-	// check if the swap will create a triple
-	makesATriple, _ := findTriples(newGrid)
-
-	if makesATriple {
-		// set all selected squares to EMPTY_GEM
-		selectionStart, selectionEnd := highLow(l.cursorGem, l.swapGem)
-		dest := l.gemGrid.IndexOf(selectionStart)
-		src := l.gemGrid.IndexOf(selectionEnd) + 1
-
-		// move squares past selection into the selection
-		for i := dest; i < l.gemGrid.LastIndex(); i++ {
-			if src >= l.gemGrid.LastIndex() {
-				setGem(&l.gemGrid, l.gemGrid.IndexToCoord(i), emptyGem)
-			} else {
-				setGem(&l.gemGrid, l.gemGrid.IndexToCoord(i), l.gemGrid.GetAtIndex(src).gem)
-
-				sqPtr := l.gemGrid.GetPtr(l.gemGrid.IndexToCoord(i))
-				sqPtr.addMover(frameCount, dropDuration,
-					l.gemGrid.IndexToCoord(src),
-					l.gemGrid.IndexToCoord(i))
-			}
-			src++
-		}
-	} else {
-		PlaySound(failOgg)
-		// penalize player for invalid move
-		// mark row to be deleted as EMPTY_GEM
-		for x := range l.gemGrid.NumColumns() {
-			l.triplesMask.Set(Coord{x, l.cursorGem.y}, false)
-		}
+func (l *LevelGems) deleteSelectionReplaceFromRight() bool {
+	l.finishCascade()
+	selection := l.selection()
+	test := l.board.Copy()
+	test.DeleteShiftLeft(selection)
+	if found, _ := test.FindMatches(); !found {
+		l.penalize()
 		return false
 	}
+	l.animate(l.board.DeleteShiftLeft(selection))
+	l.settle()
+	return true
+}
 
-	// note this is identical to bottom of fillEmpties()
-	// fill empties at the bottom of the gemGrid with newly generated gems
-	for x := range numGemColumns {
-		for y := range numGemRows {
-			if l.gemGrid.Get(Coord{x, y}).gem == emptyGem {
-				sqPtr := l.gemGrid.GetPtr(Coord{x, y})
-				sqPtr.gem = rng.Intn(l.numGems)
-				sqPtr.addMover(frameCount, dropDuration,
-					Coord{sqPtr.coords.x, numGemRows + 1},
-					Coord{x, y})
-			}
-		}
+// Delete the gem under the cursor, as x does. The gems below move up to fill the space.
+// If it does not result in a triple the delete will fail and the player is penalized.
+func (l *LevelGems) deleteGem() bool {
+	return l.makeMove(GemMove{kind: MoveDeleteGem, from: l.cursorGem})
+}
+
+// finishCascade shows the rest of the cascade at once, so a move is made on the board the player sees
+func (l *LevelGems) finishCascade() {
+	for _, step := range l.cascade {
+		l.showStep(step)
 	}
-	return true
+	l.cascade = nil
+	l.shown = l.board.Copy()
+	l.moving.SetAll(nil)
+	l.vanishing = nil
 }
 
-func (l *LevelGems) fillEmpties(frameCount int, addMover bool) {
-	moveUpFromBelow(&l.gemGrid, frameCount, addMover)
-	fillFromBelow(&l.gemGrid, l.numGems, frameCount, addMover)
+// settle refills the board after a move and resolves the cascade of matches that follows. The
+// steps of the cascade are shown one at a time, as the gems of the previous step come to rest.
+func (l *LevelGems) settle() {
+	l.hint = nil
+	l.animate(append(l.board.Collapse(), l.board.Refill()...))
+	l.shown = l.board.Copy()
+	l.cascade = l.board.Resolve()
 }
 
-// fills the entire gemGrid with random gems
-func (l *LevelGems) fillRandom() {
-	for y := range l.gemGrid.NumRows() {
-		for x := range l.gemGrid.NumColumns() {
-			setGem(&l.gemGrid, Coord{x, y}, rng.Intn(l.numGems))
+// gildBlasted turns the squares of gems blasted by special gems to gold
+func (l *LevelGems) gildBlasted(events []BoardEvent) {
+	for _, e := range events {
+		if e.kind == GemBlasted {
+			l.triplesMask.Set(e.to, true)
 		}
 	}
 }
 
-func findSquareBelow(gemGrid *Grid[Square], p Coord) Coord {
-	for y := p.y; y < numGemRows; y++ {
-		if gemGrid.Get(Coord{p.x, y}).gem != emptyGem {
-			return Coord{p.x, y}
+// showStep turns the gems matched or blasted in a cascade step to gold and awards its score
+func (l *LevelGems) showStep(step CascadeStep) {
+	for _, m := range step.matches {
+		for _, p := range m.cells {
+			l.triplesMask.Set(p, true)
 		}
 	}
-	return Coord{-1, -1} // did not find a square with color
+	l.gildBlasted(step.events)
+	l.score += step.score
+	l.chain = step.multiplier
+	l.shown = step.board.Copy()
 }
 
-func findTriples(gemGrid Grid[Square]) (bool, Grid[bool]) {
-	// create a local mask to mark all square that are in triples
-	mask := NewGridOfBools(numGemColumns, numGemRows)
+// allowsEdits returns true if the level accepts the single square edits r, R, x and ~
+func (l *LevelGems) allowsEdits() bool {
+	return l.level == LevelIdGemsReplace || l.level == LevelIdGemsEnd
+}
 
-	found := false
-	// find all horizontal triples
-	for y := range gemGrid.NumRows() {
-		for x := range gemGrid.NumColumns() - 2 {
-			p := Coord{x, y}
-			if gemGrid.Get(p).gem >= 0 { // if is a gem
-				if gemGrid.Get(p).gem == gemGrid.Get(Coord{x + 1, y}).gem && gemGrid.Get(p).gem == gemGrid.Get(Coord{x + 2, y}).gem {
-					mask.Set(p, true)
-					mask.Set(Coord{x + 1, y}, true)
-					mask.Set(Coord{x + 2, y}, true)
-					found = true
-				}
-			}
-		}
-	}
+// allowsRowEdits returns true if the level accepts the row edits J, >> and <<
+func (l *LevelGems) allowsRowEdits() bool {
+	return l.level == LevelIdGemsJoin || l.level == LevelIdGemsEnd
+}
 
-	// find all vertical triples
-	for y := range gemGrid.NumRows() - 2 {
-		for x := range gemGrid.NumColumns() {
-			p := Coord{x, y}
-			if gemGrid.Get(p).gem >= 0 { // if is a gem
-				if gemGrid.Get(p).gem == gemGrid.Get(Coord{x, y + 1}).gem && gemGrid.Get(p).gem == gemGrid.Get(Coord{x, y + 2}).gem {
-					mask.Set(p, true)
-					mask.Set(Coord{x, y + 1}, true)
-					mask.Set(Coord{x, y + 2}, true)
-					found = true
-				}
-			}
-		}
+// editCommands returns the motions and the edit commands typed in normal mode that the level accepts
+func (l *LevelGems) editCommands() []commandSpec {
+	specs := slices.Clone(gemsMotionCommands)
+	if l.allowsEdits() {
+		specs = append(specs, gemsEditCommands...)
+	}
+	if l.allowsRowEdits() {
+		specs = append(specs, gemsRowCommands...)
 	}
-	return found, mask
+	return specs
 }
 
 func (l *LevelGems) gameIsWon() bool {
-	for y := range l.gemGrid.NumRows() {
-		for x := range l.gemGrid.NumColumns() {
+	for y := range l.triplesMask.NumRows() {
+		for x := range l.triplesMask.NumColumns() {
 			if !l.triplesMask.Get(Coord{x, y}) {
 				return false
 			}
@@ -473,12 +485,12 @@ func (l *LevelGems) gameIsWon() bool {
 	return true
 }
 
-func (l *LevelGems) handleKeyNormalMode(key ebiten.Key, frameCount int) {
+func (l *LevelGems) handleKeyNormalMode(key ebiten.Key) {
 	switch key {
 	case ebiten.KeyD:
 		if equals(tail(globalKeys, 2), []ebiten.Key{ebiten.KeyD, ebiten.KeyD}) {
 			if l.level != LevelIdGemsVM {
-				l.deleteRows(1, frameCount)
+				l.deleteRows(1)
 			}
 			clearKeystrokes()
 		}
@@ -489,27 +501,15 @@ func (l *LevelGems) handleKeyNormalMode(key ebiten.Key, frameCount int) {
 				if t[0] == ebiten.KeyD {
 					n := t[1] - ebiten.Key0
 					if n > 0 && n < 10 {
-						l.deleteRows(int(n), frameCount)
+						l.deleteRows(int(n))
 						clearKeystrokes()
 					}
 				}
 			}
 		}
-	case ebiten.KeyH:
-		l.cursorGem.x = max(l.cursorGem.x-1, 0)
-		clearKeystrokes()
-	case ebiten.KeyL:
-		l.cursorGem.x = min(l.cursorGem.x+1, numGemColumns-1)
-		clearKeystrokes()
-	case ebiten.KeyK:
-		l.cursorGem.y = max(l.cursorGem.y-1, 0)
-		clearKeystrokes()
-	case ebiten.KeyJ:
-		l.cursorGem.y = min(l.cursorGem.y+1, numGemRows-1)
-		clearKeystrokes()
 	case ebiten.KeyV:
 		// entering VisualMode (where we do swaps)
-		if l.level != LevelIdGemsDD {
+		if l.level != LevelIdGemsDD && l.level != LevelIdGemsJoin {
 			l.swapGem = l.cursorGem
 			l.viMode = VisualMode
 		}
@@ -517,19 +517,121 @@ func (l *LevelGems) handleKeyNormalMode(key ebiten.Key, frameCount int) {
 	}
 }
 
-func (l *LevelGems) handleKeyVisualMode(key ebiten.Key, frameCount int) {
+// handleEditCommands handles the commands typed in normal mode, the motions h, j, k and l, the
+// single square edits r{n}, R, x and ~ and the row edits J, >> and <<, with an optional count.
+func (l *LevelGems) handleEditCommands() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		// a count followed by Enter was for d{n}, handled by handleKeyNormalMode
+		l.typed = ""
+	}
+	specs := l.editCommands()
+	for _, ch := range globalChars {
+		if l.typed == "" && !startsCommand(ch, specs) {
+			// not the start of an edit command, handled by handleKeyNormalMode
+			continue
+		}
+		l.typed += string(ch)
+		cmd, result := parseCommand(l.typed, specs)
+		switch result {
+		case commandIncomplete:
+			continue
+		case commandInvalid:
+			// a count before a command of handleKeyNormalMode, such as d, which takes none
+			l.typed = ""
+			continue
+		}
+		switch cmd.keys {
+		case "h":
+			l.cursorGem.x = max(l.cursorGem.x-cmd.Count(), 0)
+		case "l":
+			l.cursorGem.x = min(l.cursorGem.x+cmd.Count(), l.board.Columns()-1)
+		case "k":
+			l.cursorGem.y = max(l.cursorGem.y-cmd.Count(), 0)
+		case "j":
+			l.cursorGem.y = min(l.cursorGem.y+cmd.Count(), l.board.Rows()-1)
+		case "r":
+			if gem, ok := l.gemFromDigit(cmd.argument); ok {
+				l.replaceGem(l.cursorGem, gem)
+			} else {
+				PlaySound(failOgg)
+			}
+		case "R":
+			if l.outOfMoves() {
+				PlaySound(failOgg)
+				break
+			}
+			l.finishCascade()
+			l.viMode = ReplaceMode
+			l.replaceBackup = l.board.Copy()
+		case "x":
+			l.deleteGem()
+		case "~":
+			// like ~ on a letter, cycle the gem and move right
+			gem := (l.board.Gem(l.cursorGem) + 1) % l.numGems
+			if l.replaceGem(l.cursorGem, gem) {
+				l.cursorGem.x = min(l.cursorGem.x+1, l.board.Columns()-1)
+			}
+		case "J":
+			// like J, a count is the number of rows joined together
+			l.joinRows(max(cmd.Count()-1, 1))
+		case ">>":
+			l.shiftRows(cmd.Count(), 1)
+		case "<<":
+			l.shiftRows(cmd.Count(), -1)
+		}
+		l.typed = ""
+		clearKeystrokes()
+	}
+}
+
+// handleReplaceMode overwrites gems with the gem numbers typed, moving right after each.
+// The edit is checked for triples as a whole when replace mode is left with Escape.
+func (l *LevelGems) handleReplaceMode() {
+	for _, ch := range globalChars {
+		gem, ok := l.gemFromDigit(ch)
+		if !ok {
+			PlaySound(failOgg)
+			continue
+		}
+		l.board.SetGem(l.cursorGem, gem)
+		l.shown.SetGem(l.cursorGem, gem)
+		l.cursorGem.x = min(l.cursorGem.x+1, l.board.Columns()-1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		l.viMode = NormalMode
+		if found, _ := l.board.FindMatches(); found {
+			l.useMove()
+			l.settle()
+		} else {
+			// restore the squares that were overwritten
+			l.board = l.replaceBackup
+			l.shown = l.board.Copy()
+			l.penalize()
+		}
+		l.replaceBackup = nil
+	}
+	clearKeystrokes()
+}
+
+// gemFromDigit converts a typed gem number, starting at 1, to a gem
+func (l *LevelGems) gemFromDigit(ch rune) (int, bool) {
+	gem := int(ch - '1')
+	return gem, gem >= 0 && gem < l.numGems
+}
+
+func (l *LevelGems) handleKeyVisualMode(key ebiten.Key) {
 	switch key {
 	case ebiten.KeyH:
 		l.cursorGem.x = max(l.cursorGem.x-1, 0)
 		clearKeystrokes()
 	case ebiten.KeyL:
-		l.cursorGem.x = min(l.cursorGem.x+1, numGemColumns-1)
+		l.cursorGem.x = min(l.cursorGem.x+1, l.board.Columns()-1)
 		clearKeystrokes()
 	case ebiten.KeyK:
 		l.cursorGem.y = max(l.cursorGem.y-1, 0)
 		clearKeystrokes()
 	case ebiten.KeyJ:
-		l.cursorGem.y = min(l.cursorGem.y+1, numGemRows-1)
+		l.cursorGem.y = min(l.cursorGem.y+1, l.board.Rows()-1)
 		clearKeystrokes()
 	case ebiten.KeyV:
 		PlaySound(failOgg)
@@ -541,7 +643,7 @@ func (l *LevelGems) handleKeyVisualMode(key ebiten.Key, frameCount int) {
 		clearKeystrokes()
 	case ebiten.KeyD:
 		// attempt swap
-		if result := l.deleteSelectionReplaceFromBelow(frameCount); result {
+		if result := l.deleteSelectionReplaceFromBelow(); result {
 			// swap successful
 			// exiting visual mode
 			l.viMode = NormalMode
@@ -556,24 +658,36 @@ func (l *LevelGems) handleKeyVisualMode(key ebiten.Key, frameCount int) {
 
 func (l *LevelGems) Initialize(id LevelID) {
 	l.level = id
-	switch id {
-	case LevelIdGemsEnd:
-		l.numGems = 6
-		numGemColumns = 10
-	case LevelIdGemsVM:
-		l.numGems = 5
-		numGemColumns = 5
-	case LevelIdGemsDD:
-		l.numGems = 4
-		numGemColumns = 8
-	}
-	l.cursorGem = Coord{numGemColumns / 2, numGemRows / 2}
+	config := gemsLevels[id]
+	columns := config.columns
+	l.numGems = config.numGems
+	l.solver = Solver{
+		kinds:        config.moves,
+		joinGems:     columns / 2,
+		joinOverflow: config.joinOverflow,
+	}
+	l.cursorGem = Coord{columns / 2, numGemRows / 2}
 	l.swapGem = Coord{-1, -1}
-	l.gemGrid = newGridOfSquares(numGemColumns, numGemRows)
-	l.triplesMask = NewGridOfBools(numGemColumns, numGemRows)
+	l.board = NewBoard(columns, numGemRows, l.numGems, rng)
+	l.moving = NewGrid[*GemAnimation](columns, numGemRows)
+	l.vanishing = nil
+	l.particles.Clear()
+	l.triplesMask = NewGridOfBools(columns, numGemRows)
+	l.viewport = newCenteredViewport(columns, numGemRows, gemCellSize)
 
 	l.viMode = NormalMode
-	l.fillRandom()
+	l.typed = ""
+	l.board.FillSolvable(l.solver)
+	l.shown = l.board.Copy()
+	l.cascade = nil
+	l.chain = 0
+	l.hint = nil
+	l.score = 0
+	l.maxMoves = config.maxMoves
+	l.movesMade = 0
+	l.timeLimit = config.timeLimit
+	l.played = 0
+	l.failReason = ""
 	l.loadGems()
 }
 
@@ -585,103 +699,194 @@ func (l *LevelGems) loadGems() {
 			l.gemImages[i] = image
 		}
 	}
+	if len(l.specialImages) == 0 {
+		l.specialImages = map[GemKind]*ebiten.Image{
+			GemLineClear:  loadImage("resources/Gem 7.png"),
+			GemBomb:       loadImage("resources/Gem 8.png"),
+			GemColourBomb: loadImage("resources/Gem 9.png"),
+		}
+	}
+}
+
+// selection returns the gems selected in visual mode
+func (l *LevelGems) selection() BoardRange {
+	return BoardRange{l.cursorGem, l.swapGem}
 }
 
-func (l *LevelGems) Update(frameCount int) (bool, error) {
+func (l *LevelGems) Update(clock Clock) (bool, error) {
 	if isCheatKeyPressed() {
 		return true, nil
 	}
-	// clear movers if expired
-	l.gemGrid.ForEach(func(p Coord, s Square) {
-		if s.mover != nil {
-			if s.mover.endFrame < frameCount {
-				sqPtr := l.gemGrid.GetPtr(p)
-				sqPtr.mover = nil
-				l.updateTriples(frameCount)
-			}
+	l.played += clock.Step()
+	moving := l.stepAnimations(clock.Step())
+	l.particles.Update(clock.Step())
+
+	// when the gems have come to rest show the next step of the cascade
+	if !moving && len(l.cascade) > 0 {
+		step := l.cascade[0]
+		l.cascade = l.cascade[1:]
+		l.showStep(step)
+		l.chainShown = clock.Now()
+		l.animate(step.events)
+		// play triple sound unless the level is complete
+		if !l.gameIsWon() {
+			PlaySound(tripleOgg)
 		}
-	})
+	}
+
+	if l.outOfTime() {
+		// no more moves once the time is up
+		clearKeystrokes()
+	} else {
+		l.handleInput()
+	}
+
+	if l.gameIsWon() {
+		return true, nil
+	}
+	// the last move may still win the level once its cascade has been shown
+	settled := !moving && len(l.cascade) == 0
+	switch {
+	case l.outOfTime() && settled:
+		l.failReason = "You ran out of time."
+	case l.outOfMoves() && settled && l.viMode != ReplaceMode:
+		l.failReason = "You ran out of moves."
+	}
+	return false, nil
+}
+
+// handleInput handles the keys pressed and the characters typed in the current mode
+func (l *LevelGems) handleInput() {
+	if l.viMode == NormalMode && inpututil.IsKeyJustPressed(hintKey) {
+		l.showHint()
+	}
+
+	switch {
+	case l.viMode == ReplaceMode:
+		l.handleReplaceMode()
+	case l.viMode == NormalMode:
+		l.handleEditCommands()
+	}
 
 	for _, key := range globalKeys {
 		if inpututil.IsKeyJustPressed(key) {
 			switch l.viMode {
 			case NormalMode:
-				l.handleKeyNormalMode(key, frameCount)
+				l.handleKeyNormalMode(key)
 			case VisualMode:
-				l.handleKeyVisualMode(key, frameCount)
+				l.handleKeyVisualMode(key)
 			}
 		}
 	}
+}
 
-	if l.gameIsWon() {
-		return true, nil
-	}
-	return false, nil
+// Failed returns why the level was lost, once the moves or time have run out without winning
+func (l *LevelGems) Failed() (string, bool) {
+	return l.failReason, l.failReason != ""
 }
 
-func (l *LevelGems) updateTriples(frameCount int) {
-	found, mask := findTriples(l.gemGrid)
+// Score returns the points for the matches made
+func (l *LevelGems) Score() int {
+	return l.score
+}
 
-	if found {
-		// now that we have completed detecting all triples we can update the game state
-		for y := range l.gemGrid.NumRows() {
-			for x := range l.gemGrid.NumColumns() {
-				if mask.Get(Coord{x, y}) {
-					sq := l.gemGrid.GetPtr(Coord{x, y})
-					sq.gem = emptyGem
-					l.triplesMask.Set(Coord{x, y}, true)
-				}
-			}
-		}
-		// play triple sound unless the level is complete
-		if !l.gameIsWon() {
-			PlaySound(tripleOgg)
-			l.fillEmpties(frameCount, true)
-		} else {
-			// fill empties so Draw() continutes to work
-			l.fillEmpties(frameCount, false)
-			// remove all movers
-			l.gemGrid.ForEach(func(p Coord, s Square) {
-				if s.mover != nil {
-					sqPtr := l.gemGrid.GetPtr(p)
-					sqPtr.mover = nil
-					l.updateTriples(frameCount)
-				}
-			})
+// Stars rates a win by the part of the budget left, the lower rating of the moves and the time.
+// A level with no budget is not rated.
+func (l *LevelGems) Stars() int {
+	if l.maxMoves == 0 && l.timeLimit == 0 {
+		return 0
+	}
+	stars := maxStars
+	if l.maxMoves > 0 {
+		stars = min(stars, starRating(l.maxMoves-l.movesMade, l.maxMoves))
+	}
+	if l.timeLimit > 0 {
+		stars = min(stars, starRating(int((l.timeLimit-l.played)/time.Second), int(l.timeLimit/time.Second)))
+	}
+	return stars
+}
 
-		}
+// Remaining describes the moves and time left
+func (l *LevelGems) Remaining() string {
+	var parts []string
+	if l.maxMoves > 0 {
+		parts = append(parts, fmt.Sprintf("Moves %d", l.maxMoves-l.movesMade))
+	}
+	if l.timeLimit > 0 {
+		seconds := int(max(l.timeLimit-l.played, 0) / time.Second)
+		parts = append(parts, fmt.Sprintf("Time %d:%02d", seconds/60, seconds%60))
 	}
+	return strings.Join(parts, "  ")
 }
 
-func (square *Square) addMover(startFrame int, duration int, from Coord, to Coord) {
-	// add animation
-	mover := new(GemMover)
+// starRating returns 3 stars when at least two thirds of the budget is left, 2 stars when at
+// least a third is left, otherwise 1 star.
+func starRating(left, budget int) int {
+	switch {
+	case left*3 >= budget*2:
+		return 3
+	case left*3 >= budget:
+		return 2
+	}
+	return 1
+}
 
-	mover.startFrame = startFrame
-	mover.endFrame = startFrame + duration
-	mover.startGrid = from
-	mover.endCoord = to
+// outOfTime returns true if the level has a time limit and it has been played that long
+func (l *LevelGems) outOfTime() bool {
+	return l.timeLimit > 0 && l.played >= l.timeLimit
+}
 
-	square.mover = mover
+// outOfMoves returns true if the level has a move limit and every move has been used
+func (l *LevelGems) outOfMoves() bool {
+	return l.maxMoves > 0 && l.movesMade >= l.maxMoves
 }
 
-func (square *Square) drawBackground(screen *ebiten.Image, color color.Color) {
-	pos := squareToScreenPoint(square.coords)
-	vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y), gemCellSize-4, gemCellSize-4, color, false)
+// useMove counts a move that was made against the budget
+func (l *LevelGems) useMove() {
+	l.movesMade++
 }
 
-func (square *Square) drawGem(screen *ebiten.Image, gemImage *ebiten.Image, frameCount int) {
-	if square.gem >= 0 {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Scale(gemScale, gemScale)
+// Join rows below the cursor into the cursor row, as J does, once for each of joins.
+// If it does not result in a triple the join will fail and the player is penalized.
+func (l *LevelGems) joinRows(joins int) bool {
+	if l.cursorGem.y+joins >= l.board.Rows() {
+		// like J on the last line, there is nothing to join
+		PlaySound(failOgg)
+		return false
+	}
+	return l.makeMove(GemMove{kind: MoveJoinRows, from: l.cursorGem, count: joins,
+		pulled: l.solver.joinGems, overflow: l.solver.joinOverflow})
+}
 
-		// Bugbug: applyMove should be called from Update(), not Draw()
-		if square.mover != nil {
-			applyMover(square.mover, op, frameCount)
-		} else {
-			pos := squareToScreenPoint(square.coords)
-			op.GeoM.Translate(float64(pos.x), float64(pos.y))
-		}
-		screen.DrawImage(gemImage, op)
+// Shift count rows from the cursor row right by n columns, or left when n is negative, as >>
+// and << do. If it does not result in a triple the shift will fail and the player is penalized.
+func (l *LevelGems) shiftRows(count, n int) bool {
+	return l.makeMove(GemMove{kind: MoveShiftRows, from: l.cursorGem, count: count, shift: n})
+}
+
+// showHint highlights the best move on the board. The hint is kept until the board changes.
+func (l *LevelGems) showHint() {
+	if l.hint != nil {
+		return
 	}
+	l.finishCascade()
+	if move, ok := l.solver.BestMove(l.board); ok {
+		l.hint = &move
+	} else {
+		PlaySound(failOgg)
+	}
+}
+
+// penalize the player for an invalid move by removing the gold from the cursor row
+func (l *LevelGems) penalize() {
+	PlaySound(failOgg)
+	for x := range l.triplesMask.NumColumns() {
+		l.triplesMask.Set(Coord{x, l.cursorGem.y}, false)
+	}
+}
+
+// Replace the gem at p, as r does. If it does not result in a triple the replace will fail
+// and the player is penalized.
+func (l *LevelGems) replaceGem(p Coord, gem int) bool {
+	return l.makeMove(GemMove{kind: MoveReplaceGem, from: p, gem: gem})
 }
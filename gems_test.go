@@ -1,3 +1,113 @@
 package main
 
-//
+import (
+	"testing"
+	"time"
+)
+
+func TestStarRating(t *testing.T) {
+	tests := []struct {
+		name   string
+		left   int
+		budget int
+		want   int
+	}{
+		{"Untouched", 30, 30, 3},
+		{"Two thirds left", 20, 30, 3},
+		{"Just under two thirds", 19, 30, 2},
+		{"A third left", 10, 30, 2},
+		{"Just under a third", 9, 30, 1},
+		{"Nothing left", 0, 30, 1},
+		{"Overspent time", -5, 30, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := starRating(tt.left, tt.budget); got != tt.want {
+				t.Errorf("starRating(%d, %d) = %d, want %d", tt.left, tt.budget, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeMoveBudget(t *testing.T) {
+	newLevel := func(movesMade int) *LevelGems {
+		board := newTestBoard([][]int{{0, 0, 1, 2}})
+		return &LevelGems{
+			board:       board,
+			moving:      NewGrid[*GemAnimation](board.Columns(), board.Rows()),
+			triplesMask: NewGridOfBools(board.Columns(), board.Rows()),
+			maxMoves:    2,
+			movesMade:   movesMade,
+		}
+	}
+	tests := []struct {
+		name          string
+		movesMade     int
+		wantMovesMade int
+	}{
+		{"Rejected move is free", 0, 0},
+		{"Out of moves", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLevel(tt.movesMade)
+			// a 1 by the 0s makes no triple
+			if l.makeMove(GemMove{kind: MoveReplaceGem, from: Coord{3, 0}, gem: 1}) {
+				t.Fatal("makeMove() = true for a move with no triple")
+			}
+			if l.movesMade != tt.wantMovesMade {
+				t.Errorf("movesMade = %d, want %d", l.movesMade, tt.wantMovesMade)
+			}
+		})
+	}
+}
+
+func TestCountedMotions(t *testing.T) {
+	tests := []struct {
+		name  string
+		typed string
+		want  Coord
+	}{
+		{"One down", "j", Coord{2, 5}},
+		{"Three down", "3j", Coord{2, 7}},
+		{"Two left", "2h", Coord{0, 4}},
+		{"Past the edge", "9l", Coord{3, 4}},
+		{"A count waits for the motion", "1", Coord{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := newTestBoard([][]int{{0, 1, 2, 3}})
+			l := &LevelGems{board: board, level: LevelIdGemsDD, cursorGem: Coord{2, 4}}
+			globalChars = []rune(tt.typed)
+			defer func() { globalChars = nil }()
+			l.handleEditCommands()
+			if l.cursorGem != tt.want {
+				t.Errorf("cursor after %q = %v, want %v", tt.typed, l.cursorGem, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRunsOutAfterCascade(t *testing.T) {
+	board := newTestBoard([][]int{{0, 1, 2, 3}})
+	step := CascadeStep{board: board, multiplier: 1}
+	l := &LevelGems{
+		board:       board,
+		moving:      NewGrid[*GemAnimation](board.Columns(), board.Rows()),
+		triplesMask: NewGridOfBools(board.Columns(), board.Rows()),
+		timeLimit:   time.Minute,
+		played:      time.Minute,
+		cascade:     []CascadeStep{step, step},
+	}
+	var clock FakeClock
+	clock.Advance(tick)
+	l.Update(&clock)
+	if _, failed := l.Failed(); failed {
+		t.Fatal("Failed() = true while the last cascade is still showing")
+	}
+	clock.Advance(tick)
+	l.Update(&clock)
+	if reason, failed := l.Failed(); !failed {
+		t.Errorf("Failed() = %q, false once the cascade has been shown, want true", reason)
+	}
+}
@@ -15,6 +15,15 @@ type Coord struct {
 	x, y int
 }
 
+// NewGrid creates a new Grid with the specified width and height, filled with the zero value of T.
+func NewGrid[T comparable](width, height int) Grid[T] {
+	r := make([][]T, height)
+	for i := range r {
+		r[i] = make([]T, width)
+	}
+	return r
+}
+
 // NewGridOfBools creates a new Grid of booleans with the specified width and height.
 func NewGridOfBools(width, height int) Grid[bool] {
 	r := make([][]bool, height)
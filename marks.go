@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+/*
+ * LevelMarks provides practice of marks and jumps on a board too large to cross quickly
+ * with h, j, k and l. The player marks gems with m{a-z}, returns to them with '{a-z} and
+ * `{a-z}, and moves through the jump list with Ctrl-O, Ctrl-I and ''.
+ */
+
+const (
+	marksCellSize    = 20
+	marksColumns     = screenWidth / marksCellSize
+	marksRows        = 26
+	marksNumGems     = 4
+	marksTimeLimit   = 3 * time.Second // allowed to earn the bonus on a timed objective
+	marksBonusPoints = 3
+	maxPlaceAttempts = 1000 // random squares tried for the gems before they are put in the fallback places
+	maxJumps         = 100  // vi keeps the last 100 jumps
+)
+
+var (
+	colorMarkLetter  = darkButter
+	colorMarksGrid   = color.RGBA{0x30, 0x44, 0x58, 0xff}
+	colorMarksTarget = color.RGBA{0xff, 0xff, 0x80, 0x60} // translucent lightGold
+	colorStatusBar   = mediumCoal
+)
+
+// marksFallbackGems are far enough apart, for when random places can't be found
+var marksFallbackGems = []Coord{{8, 3}, {32, 6}, {12, 20}, {36, 24}}
+
+var marksCommands = []commandSpec{
+	{keys: "h"}, {keys: "j"}, {keys: "k"}, {keys: "l"},
+	{keys: "0"}, {keys: "$"}, {keys: "G"}, {keys: "gg"},
+	{keys: "m", argument: true},
+	{keys: "'", argument: true},
+	{keys: "`", argument: true},
+}
+
+// JumpList records the positions jumped from so they can be revisited with Ctrl-O and Ctrl-I.
+type JumpList struct {
+	jumps []Coord
+	index int // position in jumps of the entry Ctrl-O and Ctrl-I move from, len(jumps) when at the newest
+}
+
+// Push records a jump from a position, dropping any older entry for the same position.
+func (j *JumpList) Push(from Coord) {
+	for i, p := range j.jumps {
+		if p == from {
+			j.jumps = append(j.jumps[:i], j.jumps[i+1:]...)
+			break
+		}
+	}
+	j.jumps = append(j.jumps, from)
+	if len(j.jumps) > maxJumps {
+		j.jumps = j.jumps[1:]
+	}
+	j.index = len(j.jumps)
+}
+
+// Back moves to the previous entry, as Ctrl-O does. When at the newest entry the current position
+// is recorded first so Ctrl-I can return to it.
+func (j *JumpList) Back(current Coord) (Coord, bool) {
+	if j.index == len(j.jumps) {
+		j.Push(current)
+		j.index = len(j.jumps) - 1
+	}
+	if j.index == 0 {
+		return current, false
+	}
+	j.index--
+	return j.jumps[j.index], true
+}
+
+// Forward moves to the next entry, as Ctrl-I does.
+func (j *JumpList) Forward(current Coord) (Coord, bool) {
+	if j.index >= len(j.jumps)-1 {
+		return current, false
+	}
+	j.index++
+	return j.jumps[j.index], true
+}
+
+type objectiveKind int
+
+const (
+	objectiveSetMark objectiveKind = iota
+	objectiveGoToLine
+	objectiveGoTo
+	objectiveJumpBack
+)
+
+// markObjective is one of the tasks the player completes in order.
+type markObjective struct {
+	kind   objectiveKind
+	mark   rune
+	target Coord
+	timed  bool
+}
+
+type LevelMarks struct {
+	cursor         Coord
+	gemImages      []*ebiten.Image
+	gems           []Coord
+	jumps          JumpList
+	level          LevelID
+	marks          map[rune]Coord
+	message        string
+	objective      int
+	objectiveStart time.Duration // game time the current objective started
+	objectives     []markObjective
+	previous       Coord // position before the latest jump, the target of ''
+	score          int
+	typed          string
+}
+
+var marksFace font.Face
+
+func (l *LevelMarks) Draw(screen *ebiten.Image, now time.Duration) {
+	screen.Fill(darkCoal)
+
+	// draw a faint grid so distances can be judged
+	for y := range marksRows {
+		for x := range marksColumns {
+			if (x+y)%2 == 0 {
+				vector.DrawFilledRect(screen, float32(x*marksCellSize), float32(y*marksCellSize),
+					marksCellSize, marksCellSize, colorMarksGrid, false)
+			}
+		}
+	}
+
+	// highlight the target of the current objective
+	if o, ok := l.currentObjective(); ok {
+		switch o.kind {
+		case objectiveGoToLine:
+			vector.DrawFilledRect(screen, 0, float32(o.target.y*marksCellSize),
+				screenWidth, marksCellSize, colorMarksTarget, false)
+		default:
+			l.drawCell(screen, o.target, colorMarksTarget)
+		}
+	}
+
+	for i, p := range l.gems {
+		op := &ebiten.DrawImageOptions{}
+		scale := float64(marksCellSize) / float64(gemWidth)
+		op.GeoM.Scale(scale, scale)
+		op.GeoM.Translate(float64(p.x*marksCellSize), float64(p.y*marksCellSize))
+		screen.DrawImage(l.gemImages[i], op)
+	}
+
+	// draw the cursor
+	cursorColors := [2]color.Color{redCursor, whiteCursor}
+	blink := blinkPhase(now, blinkInterval)
+	l.drawCell(screen, l.cursor, cursorColors[blink])
+
+	// marks are shown as letters
+	for mark, p := range l.marks {
+		text.Draw(screen, string(mark), marksFace,
+			p.x*marksCellSize+5, p.y*marksCellSize+marksCellSize-4, colorMarkLetter)
+	}
+
+	l.drawStatus(screen, now)
+}
+
+func (l *LevelMarks) drawCell(screen *ebiten.Image, p Coord, clr color.Color) {
+	vector.DrawFilledRect(screen, float32(p.x*marksCellSize), float32(p.y*marksCellSize),
+		marksCellSize, marksCellSize, clr, false)
+}
+
+func (l *LevelMarks) drawStatus(screen *ebiten.Image, now time.Duration) {
+	top := marksRows * marksCellSize
+	vector.DrawFilledRect(screen, 0, float32(top), screenWidth, screenHeight-float32(top), colorStatusBar, false)
+
+	o, ok := l.currentObjective()
+	if ok {
+		drawBufferString(screen, l.objectiveText(o), 20, top+8, 0, 0, bufferText)
+		if o.timed {
+			// the timer bar shrinks until the bonus is lost
+			remaining := max(marksTimeLimit-(now-l.objectiveStart), 0)
+			width := float32(remaining) / float32(marksTimeLimit) * (screenWidth - 40)
+			vector.DrawFilledRect(screen, 20, float32(top+40), width, 6, lightGold, false)
+		}
+	}
+	drawBufferString(screen, fmt.Sprintf("Score %d", l.score), 20, top+48, 0, 0, bufferDimText)
+	drawBufferString(screen, l.typed, screenWidth-120, top+48, 0, 0, bufferText)
+	drawBufferString(screen, l.message, 200, top+48, 0, 0, bufferDimText)
+}
+
+func (l *LevelMarks) Initialize(id LevelID) {
+	l.level = id
+	l.cursor = Coord{0, 0}
+	l.previous = l.cursor
+	l.jumps = JumpList{}
+	l.marks = make(map[rune]Coord)
+	l.score = 0
+	l.typed = ""
+	l.message = ""
+	l.placeGems()
+	l.objectives = newMarkObjectives(l.gems)
+	l.objective = 0
+	l.objectiveStart = 0
+	if marksFace == nil {
+		marksFace = newMonoFace(16)
+	}
+	if len(l.gemImages) == 0 {
+		for i := range marksNumGems {
+			l.gemImages = append(l.gemImages, loadImage("resources/Gem "+strconv.Itoa(i+1)+".png"))
+		}
+	}
+}
+
+func (l *LevelMarks) Update(clock Clock) (bool, error) {
+	// c is a mark name so only allow the cheat key when no command is being typed
+	if l.typed == "" && isCheatKeyPressed() {
+		return true, nil
+	}
+	if l.objectiveStart == 0 {
+		l.objectiveStart = clock.Now()
+	}
+
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl)
+	switch {
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyO):
+		if p, ok := l.jumps.Back(l.cursor); ok {
+			l.moveByJump(p)
+		} else {
+			PlaySound(failOgg)
+		}
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyI), inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		// Ctrl-I and Tab are the same key in a terminal
+		if p, ok := l.jumps.Forward(l.cursor); ok {
+			l.moveByJump(p)
+		} else {
+			PlaySound(failOgg)
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		l.typed = ""
+	case !ctrl:
+		for _, ch := range globalChars {
+			l.typed += string(ch)
+			cmd, result := parseCommand(l.typed, marksCommands)
+			switch result {
+			case commandComplete:
+				l.execute(cmd)
+				l.typed = ""
+			case commandInvalid:
+				PlaySound(failOgg)
+				l.typed = ""
+			}
+		}
+	}
+	clearKeystrokes()
+
+	l.checkObjective(clock.Now())
+	return l.objective >= len(l.objectives), nil
+}
+
+// checkObjective scores the current objective if it has been completed and starts the next.
+func (l *LevelMarks) checkObjective(now time.Duration) {
+	o, ok := l.currentObjective()
+	if !ok {
+		return
+	}
+	done := false
+	switch o.kind {
+	case objectiveSetMark:
+		p, marked := l.marks[o.mark]
+		done = marked && p == o.target
+	case objectiveGoToLine:
+		done = l.cursor.y == o.target.y
+	case objectiveGoTo, objectiveJumpBack:
+		done = l.cursor == o.target
+	}
+	if !done {
+		return
+	}
+
+	points := 1
+	if o.timed && now-l.objectiveStart <= marksTimeLimit {
+		points = marksBonusPoints
+		l.message = "Fast!"
+	} else {
+		l.message = ""
+	}
+	l.score += points
+	PlaySound(tripleOgg)
+
+	l.objective++
+	l.objectiveStart = now
+	if next, ok := l.currentObjective(); ok && next.kind == objectiveJumpBack {
+		// the target of a jump back is wherever the player jumped from
+		l.objectives[l.objective].target = l.previous
+	}
+}
+
+func (l *LevelMarks) currentObjective() (markObjective, bool) {
+	if l.objective >= len(l.objectives) {
+		return markObjective{}, false
+	}
+	return l.objectives[l.objective], true
+}
+
+func (l *LevelMarks) execute(cmd viCommand) {
+	switch cmd.keys {
+	case "h":
+		l.cursor.x = max(l.cursor.x-cmd.Count(), 0)
+	case "l":
+		l.cursor.x = min(l.cursor.x+cmd.Count(), marksColumns-1)
+	case "k":
+		l.cursor.y = max(l.cursor.y-cmd.Count(), 0)
+	case "j":
+		l.cursor.y = min(l.cursor.y+cmd.Count(), marksRows-1)
+	case "0":
+		l.cursor.x = 0
+	case "$":
+		l.cursor.x = marksColumns - 1
+	case "G":
+		// G goes to the last line, or to the line given by the count
+		line := marksRows - 1
+		if cmd.count > 0 {
+			line = min(cmd.count, marksRows) - 1
+		}
+		l.jumpTo(Coord{0, line})
+	case "gg":
+		l.jumpTo(Coord{0, max(cmd.count, 1) - 1})
+	case "m":
+		if cmd.argument < 'a' || cmd.argument > 'z' {
+			PlaySound(failOgg)
+			return
+		}
+		l.marks[cmd.argument] = l.cursor
+	case "'", "`":
+		var p Coord
+		switch {
+		case cmd.argument == '\'' || cmd.argument == '`':
+			// '' and `` return to the position before the latest jump
+			p = l.previous
+		default:
+			var ok bool
+			p, ok = l.marks[cmd.argument]
+			if !ok {
+				l.message = errMarkNotSet.Error()
+				PlaySound(failOgg)
+				return
+			}
+		}
+		if cmd.keys == "'" {
+			// ' jumps to the start of the marked line rather than the marked column
+			p.x = 0
+		}
+		l.jumpTo(p)
+	}
+}
+
+// jumpTo moves the cursor to p, remembering where it came from in the jump list.
+func (l *LevelMarks) jumpTo(p Coord) {
+	l.jumps.Push(l.cursor)
+	l.moveByJump(p)
+}
+
+// moveByJump moves the cursor to p. Every jump, Ctrl-O and Ctrl-I included, sets the previous
+// context mark, the position a quote typed twice returns to, as it does in vi. A jump that does
+// not land on the target of a jump back objective leaves a new place to jump back to.
+func (l *LevelMarks) moveByJump(p Coord) {
+	l.previous = l.cursor
+	l.cursor = p
+	if o, ok := l.currentObjective(); ok && o.kind == objectiveJumpBack && p != o.target {
+		l.objectives[l.objective].target = l.previous
+	}
+}
+
+func (l *LevelMarks) objectiveText(o markObjective) string {
+	switch o.kind {
+	case objectiveSetMark:
+		return fmt.Sprintf("Move to the gem and mark it with m%c", o.mark)
+	case objectiveGoToLine:
+		return fmt.Sprintf("Go to the line of mark %c", o.mark)
+	case objectiveGoTo:
+		return fmt.Sprintf("Go to the gem marked %c", o.mark)
+	case objectiveJumpBack:
+		return "Jump back to where you were"
+	}
+	return ""
+}
+
+// placeGems scatters the gems across the board, keeping them apart so marks are worth using.
+func (l *LevelMarks) placeGems() {
+	l.gems = l.gems[:0]
+	for range maxPlaceAttempts {
+		if len(l.gems) == marksNumGems {
+			return
+		}
+		p := Coord{rng.Intn(marksColumns), rng.Intn(marksRows)}
+		farEnough := p.x+p.y > 10
+		for _, g := range l.gems {
+			if abs(g.x-p.x)+abs(g.y-p.y) < 20 || g.y == p.y {
+				farEnough = false
+			}
+		}
+		if farEnough {
+			l.gems = append(l.gems, p)
+		}
+	}
+	if len(l.gems) < marksNumGems {
+		l.gems = append(l.gems[:0], marksFallbackGems...)
+	}
+}
+
+// newMarkObjectives creates the objectives for a board. The player first marks every gem,
+// then is timed returning to them, alternating between ' and ` and jumping back between them.
+func newMarkObjectives(gems []Coord) []markObjective {
+	var objectives []markObjective
+	for i, p := range gems {
+		objectives = append(objectives, markObjective{kind: objectiveSetMark, mark: rune('a' + i), target: p})
+	}
+	order := rng.Perm(len(gems))
+	for i, g := range order {
+		kind := objectiveGoTo
+		if i%2 == 1 {
+			kind = objectiveGoToLine
+		}
+		objectives = append(objectives, markObjective{kind: kind, mark: rune('a' + g), target: gems[g], timed: true})
+		if i%2 == 0 {
+			objectives = append(objectives, markObjective{kind: objectiveJumpBack, timed: true})
+		}
+	}
+	return objectives
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+package main
+
+import "testing"
+
+func TestJumpList(t *testing.T) {
+	var j JumpList
+	a, b, c, d := Coord{1, 1}, Coord{2, 2}, Coord{3, 3}, Coord{4, 4}
+
+	// Ctrl-O with no jumps does nothing
+	if _, ok := j.Back(a); ok {
+		t.Errorf("Back() on an empty jump list succeeded")
+	}
+
+	// jump a -> b -> c -> d
+	j = JumpList{}
+	j.Push(a)
+	j.Push(b)
+	j.Push(c)
+
+	steps := []struct {
+		back bool
+		want Coord
+		ok   bool
+	}{
+		{true, c, true},
+		{true, b, true},
+		{true, a, true},
+		{true, a, false}, // at the oldest jump
+		{false, b, true},
+		{false, c, true},
+		{false, d, true}, // Ctrl-O recorded d so Ctrl-I can return to it
+		{false, d, false},
+	}
+	current := d
+	for i, s := range steps {
+		var got Coord
+		var ok bool
+		if s.back {
+			got, ok = j.Back(current)
+		} else {
+			got, ok = j.Forward(current)
+		}
+		if got != s.want || ok != s.ok {
+			t.Fatalf("step %d: got %v, %v; want %v, %v", i, got, ok, s.want, s.ok)
+		}
+		current = got
+	}
+}
+
+func TestJumpListDropsDuplicates(t *testing.T) {
+	var j JumpList
+	a, b := Coord{1, 1}, Coord{2, 2}
+	j.Push(a)
+	j.Push(b)
+	j.Push(a)
+	if len(j.jumps) != 2 || j.jumps[0] != b || j.jumps[1] != a {
+		t.Errorf("jumps = %v; want [%v %v]", j.jumps, b, a)
+	}
+
+	for i := range maxJumps + 10 {
+		j.Push(Coord{i, 0})
+	}
+	if len(j.jumps) != maxJumps {
+		t.Errorf("len(jumps) = %d; want %d", len(j.jumps), maxJumps)
+	}
+}
+
+func TestJumpBackFollowsLatestJump(t *testing.T) {
+	seedRNG(1)
+	back := viCommand{keys: "`", argument: '`'}
+	tests := []struct {
+		name  string
+		cmds  []viCommand
+		ctrlO bool
+	}{
+		{"Straight back", []viCommand{back}, false},
+		{"Moved first", []viCommand{{keys: "l", count: 3}, back}, false},
+		{"Another jump first", []viCommand{{keys: "G"}, back}, false},
+		{"Ctrl-O", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LevelMarks{marks: map[rune]Coord{}}
+			l.cursor = Coord{5, 5}
+			l.jumpTo(Coord{20, 10})
+			l.objectives = []markObjective{{kind: objectiveJumpBack, target: l.previous}}
+			for _, cmd := range tt.cmds {
+				l.execute(cmd)
+			}
+			if tt.ctrlO {
+				p, _ := l.jumps.Back(l.cursor)
+				l.moveByJump(p)
+			}
+			l.checkObjective(0)
+			if l.objective != 1 {
+				t.Errorf("jump back not done with the cursor at %v and the target at %v", l.cursor, l.objectives[0].target)
+			}
+		})
+	}
+}
+
+func TestPlaceGems(t *testing.T) {
+	seedRNG(1)
+	l := &LevelMarks{}
+	l.placeGems()
+	for name, gems := range map[string][]Coord{"placed": l.gems, "fallback": marksFallbackGems} {
+		if len(gems) != marksNumGems {
+			t.Errorf("%s: %d gems, want %d", name, len(gems), marksNumGems)
+		}
+		for i, p := range gems {
+			if p.x+p.y <= 10 || p.x >= marksColumns || p.y >= marksRows {
+				t.Errorf("%s: gem at %v is too near the start or off the board", name, p)
+			}
+			for _, q := range gems[i+1:] {
+				if abs(p.x-q.x)+abs(p.y-q.y) < 20 || p.y == q.y {
+					t.Errorf("%s: gems at %v and %v are too close", name, p, q)
+				}
+			}
+		}
+	}
+}
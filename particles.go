@@ -0,0 +1,180 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+/*
+ * Particles are small squares thrown out in bursts when something breaks, is eaten or is hit. An
+ * emitter preset says how many particles a burst has, how fast and in which directions they are
+ * thrown, how gravity pulls them and how their color and alpha change over their life. Particles
+ * live in a slice allocated once, up to a hard cap, so bursts never allocate or slow the game down
+ * however many things break at once. A burst that would go over the cap is cut short.
+ */
+
+const maxParticles = 400 // particles alive at once in a level
+
+// ParticlePreset is how an emitter throws out a burst of particles
+type ParticlePreset struct {
+	count      int           // particles in a burst
+	minSpeed   float64       // pixels a tick
+	maxSpeed   float64       // pixels a tick
+	angle      float64       // direction the burst is thrown in, in radians clockwise from the right
+	spread     float64       // how far either side of angle a particle can be thrown
+	gravity    float64       // added to the downward speed of a particle each tick
+	minLife    time.Duration // the shortest a particle lives
+	maxLife    time.Duration // the longest a particle lives
+	size       float64       // width and height
+	colors     []color.RGBA  // the color over the life of a particle, from first to last
+	fade       bool          // fade out over the life of a particle
+	shrink     bool          // shrink to nothing over the life of a particle
+	tintsFirst bool          // the tint of a burst replaces the first color
+}
+
+// particlePresets are the bursts a level can emit
+var particlePresets = map[string]ParticlePreset{
+	// gems of a match, tinted with the color of the gem
+	"match": {count: 12, minSpeed: 1, maxSpeed: 3, spread: math.Pi, gravity: 0.05, minLife: 330 * time.Millisecond, maxLife: 580 * time.Millisecond,
+		size: 5, colors: []color.RGBA{lightAluminium, lightAluminium}, fade: true, tintsFirst: true},
+	// a broken brick, tinted with the color of the brick
+	"brick": {count: 10, minSpeed: 1, maxSpeed: 2.5, spread: math.Pi, gravity: 0.15, minLife: 420 * time.Millisecond, maxLife: 670 * time.Millisecond,
+		size: 6, colors: []color.RGBA{lightAluminium, mediumCoal}, shrink: true, tintsFirst: true},
+	// food eaten by a snake, tinted with the color of the food
+	"eat": {count: 8, minSpeed: 0.5, maxSpeed: 2, spread: math.Pi, minLife: 250 * time.Millisecond, maxLife: 420 * time.Millisecond,
+		size: 4, colors: []color.RGBA{lightButter, lightButter}, fade: true, tintsFirst: true},
+	// the pufferfish hitting a pipe, a spray of bubbles
+	"crash": {count: 16, minSpeed: 1.5, maxSpeed: 4, spread: math.Pi, gravity: -0.05, minLife: 330 * time.Millisecond, maxLife: 670 * time.Millisecond,
+		size: 4, colors: []color.RGBA{lightAluminium, lightSkyBlue, darkSkyBlue}, fade: true},
+}
+
+// Particle is one particle of a burst
+type Particle struct {
+	x, y   float64
+	dx, dy float64
+	age    time.Duration
+	life   time.Duration
+	first  color.RGBA // the first color, the tint of the burst or of the preset
+	preset *ParticlePreset
+}
+
+// ParticleSystem moves and draws the particles of a level. The zero value is ready to use.
+type ParticleSystem struct {
+	particles []Particle
+	pixel     *ebiten.Image // scaled and colored to draw each particle
+	rng       *rand.Rand    // apart from the game's so particles don't change what a seed plays
+}
+
+// Emit throws out a burst of the named preset from x, y. A tint, when not nil, replaces the first
+// color of a preset that takes one. Returns the number of particles emitted, fewer than the
+// preset's count once the system is full.
+func (s *ParticleSystem) Emit(name string, x, y float64, tint color.Color) int {
+	preset, ok := particlePresets[name]
+	if !ok {
+		log.Fatalf("Unknown particle preset %q", name)
+	}
+	if s.particles == nil {
+		s.particles = make([]Particle, 0, maxParticles)
+		s.rng = rand.New(rand.NewSource(1))
+	}
+	first := preset.colors[0]
+	if tint != nil && preset.tintsFirst {
+		first = color.RGBAModel.Convert(tint).(color.RGBA)
+	}
+	n := min(preset.count, maxParticles-len(s.particles))
+	for range n {
+		angle := preset.angle + (s.rng.Float64()*2-1)*preset.spread
+		speed := preset.minSpeed + s.rng.Float64()*(preset.maxSpeed-preset.minSpeed)
+		s.particles = append(s.particles, Particle{
+			x:      x,
+			y:      y,
+			dx:     math.Cos(angle) * speed,
+			dy:     math.Sin(angle) * speed,
+			life:   preset.minLife + time.Duration(s.rng.Int63n(int64(preset.maxLife-preset.minLife)+1)),
+			first:  first,
+			preset: &preset,
+		})
+	}
+	return n
+}
+
+// Update moves the particles on by dt of game time and removes those that have lived their life
+func (s *ParticleSystem) Update(dt time.Duration) {
+	steps := ticks(dt)
+	alive := s.particles[:0]
+	for _, p := range s.particles {
+		p.age += dt
+		if p.age >= p.life {
+			continue
+		}
+		p.dy += p.preset.gravity * steps
+		p.x += p.dx * steps
+		p.y += p.dy * steps
+		alive = append(alive, p)
+	}
+	s.particles = alive
+}
+
+// Clear removes all the particles, keeping the pool
+func (s *ParticleSystem) Clear() {
+	s.particles = s.particles[:0]
+}
+
+// Len returns the number of particles alive
+func (s *ParticleSystem) Len() int {
+	return len(s.particles)
+}
+
+func (s *ParticleSystem) Draw(screen *ebiten.Image) {
+	if len(s.particles) == 0 {
+		return
+	}
+	if s.pixel == nil {
+		s.pixel = ebiten.NewImage(1, 1)
+		s.pixel.Fill(color.White)
+	}
+	for _, p := range s.particles {
+		t := float64(p.age) / float64(p.life)
+		size := p.preset.size
+		if p.preset.shrink {
+			size *= 1 - t
+		}
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(size, size)
+		op.GeoM.Translate(p.x-size/2, p.y-size/2)
+		op.ColorScale.ScaleWithColor(particleColor(p.first, p.preset.colors[1:], t))
+		if p.preset.fade {
+			op.ColorScale.ScaleAlpha(float32(1 - t))
+		}
+		screen.DrawImage(s.pixel, op)
+	}
+}
+
+// particleColor returns the color a particle is at t, from 0 to 1, through its life, blending
+// evenly from first through the rest of the colors
+func particleColor(first color.RGBA, rest []color.RGBA, t float64) color.RGBA {
+	if len(rest) == 0 {
+		return first
+	}
+	t = limitToRange(t, 0, 1)
+	pos := t * float64(len(rest))
+	i := min(int(pos), len(rest)-1)
+	from := first
+	if i > 0 {
+		from = rest[i-1]
+	}
+	return lerpColor(from, rest[i], pos-float64(i))
+}
+
+// lerpColor returns the color t, from 0 to 1, of the way from a to b
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), lerp(a.A, b.A)}
+}
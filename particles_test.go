@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParticleColor(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	tests := []struct {
+		name  string
+		first color.RGBA
+		rest  []color.RGBA
+		t     float64
+		want  color.RGBA
+	}{
+		{"one color", red, nil, 0.5, red},
+		{"start", black, []color.RGBA{white}, 0, black},
+		{"halfway", black, []color.RGBA{white}, 0.5, color.RGBA{0x80, 0x80, 0x80, 0xff}},
+		{"end", black, []color.RGBA{white}, 1, white},
+		{"past the end", black, []color.RGBA{white}, 1.5, white},
+		{"second of three", black, []color.RGBA{white, red}, 0.5, white},
+		{"into the third", black, []color.RGBA{white, red}, 0.75, color.RGBA{0xff, 0x80, 0x80, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := particleColor(tt.first, tt.rest, tt.t); got != tt.want {
+				t.Errorf("particleColor(%v, %v, %v) = %v, want %v", tt.first, tt.rest, tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParticleLife(t *testing.T) {
+	var s ParticleSystem
+	preset := particlePresets["brick"]
+	if n := s.Emit("brick", 100, 100, lightGreen); n != preset.count {
+		t.Fatalf("emitted %d particles, want %d", n, preset.count)
+	}
+	if s.particles[0].first != lightGreen {
+		t.Errorf("first color %v, want the tint %v", s.particles[0].first, lightGreen)
+	}
+	s.Update(preset.minLife - 1)
+	if s.Len() != preset.count {
+		t.Errorf("%d particles alive before the shortest life, want %d", s.Len(), preset.count)
+	}
+	s.Update(preset.maxLife - preset.minLife + 1)
+	if s.Len() != 0 {
+		t.Errorf("%d particles alive after the longest life, want none", s.Len())
+	}
+}
+
+func TestParticleGravity(t *testing.T) {
+	var s ParticleSystem
+	s.Emit("brick", 100, 100, nil)
+	p := s.particles[0]
+	s.Update(2 * tick)
+	if got, want := s.particles[0].dy, p.dy+2*p.preset.gravity; got != want {
+		t.Errorf("dy after two ticks is %v, want %v", got, want)
+	}
+	if got, want := s.particles[0].y, p.y+2*(p.dy+2*p.preset.gravity); got != want {
+		t.Errorf("y after two ticks is %v, want %v", got, want)
+	}
+	s.Update(0)
+	if s.particles[0].y != p.y+2*(p.dy+2*p.preset.gravity) {
+		t.Error("a particle moved while the clock was paused")
+	}
+}
+
+func TestParticleCap(t *testing.T) {
+	var s ParticleSystem
+	emitted := 0
+	for range maxParticles {
+		emitted += s.Emit("crash", 0, 0, nil)
+	}
+	if emitted != maxParticles || s.Len() != maxParticles || cap(s.particles) != maxParticles {
+		t.Errorf("emitted %d, %d alive in a pool of %d, want %d", emitted, s.Len(), cap(s.particles), maxParticles)
+	}
+	if n := s.Emit("crash", 0, 0, nil); n != 0 {
+		t.Errorf("emitted %d particles into a full system", n)
+	}
+	s.Clear()
+	if s.Len() != 0 || cap(s.particles) != maxParticles {
+		t.Errorf("after Clear %d alive in a pool of %d", s.Len(), cap(s.particles))
+	}
+}
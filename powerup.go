@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/*
+ * Power-ups in the bricks levels. A power-up brick drops a letter when it breaks. Catching the
+ * letter with the paddle holds the power-up until the player types its vi command:
+ *
+ *	w   widens the paddle for a while, as w moves forward a word
+ *	3l  dashes the paddle three paddle widths, any count with h or l dashes that many widths
+ *	y   yanks a copy of each ball, for multi-ball
+ *	u   undoes the loss of the last ball lost
+ */
+
+// PowerUpKind is the effect of a power-up
+type PowerUpKind int
+
+const (
+	PowerUpWiden PowerUpKind = iota
+	PowerUpDash
+	PowerUpMultiBall
+	PowerUpRestore
+	numPowerUps
+)
+
+const (
+	dropSize      = 30
+	powerUpPoints = 25               // for catching a power-up
+	dropSpeed     = 2                // pixels a tick
+	widenDuration = 15 * time.Second // the paddle stays wide
+	restoreWait   = 5 * time.Second  // to type u once the last ball is lost, before a life is lost
+	widenFactor   = 1.5
+)
+
+// powerUpKeys is the command shown for each power-up
+var powerUpKeys = map[PowerUpKind]string{
+	PowerUpWiden:     "w",
+	PowerUpDash:      "3l",
+	PowerUpMultiBall: "y",
+	PowerUpRestore:   "u",
+}
+
+var bricksCommands = []commandSpec{
+	{keys: "w"},
+	{keys: "h"},
+	{keys: "l"},
+	{keys: "y"},
+	{keys: "u"},
+}
+
+// PowerUpDrop is a power-up letter falling from a broken brick
+type PowerUpDrop struct {
+	kind PowerUpKind
+	x, y float32 // the center of the letter
+}
+
+// dropPowerUp drops a random power-up from x, y
+func (l *LevelBricksHL) dropPowerUp(x, y float32) {
+	l.drops = append(l.drops, PowerUpDrop{kind: PowerUpKind(rng.Intn(int(numPowerUps))), x: x, y: y})
+}
+
+// updateDrops moves the falling letters down, holding the ones caught by the bottom paddle.
+// It also ends a widened paddle once its time is up.
+func (l *LevelBricksHL) updateDrops(now, step time.Duration) {
+	paddle := Rect{l.paddlesX, screenHeight - paddlesXHeight, l.paddleWidth, paddlesXHeight}
+	drops := l.drops[:0]
+	for _, d := range l.drops {
+		d.y += dropSpeed * float32(ticks(step))
+		if _, caught := touchCircle(d.x, d.y, dropSize/2, paddle); caught {
+			l.held = append(l.held, d.kind)
+			l.scoreboard.Add(powerUpPoints, d.x, paddle.top-dropSize, now)
+			PlaySound(tripleOgg)
+		} else if d.y-dropSize/2 < screenHeight {
+			drops = append(drops, d)
+		}
+	}
+	l.drops = drops
+
+	if l.widenedUntil > 0 && now >= l.widenedUntil {
+		l.resizePaddle(paddlesXWidth)
+		l.widenedUntil = 0
+	}
+}
+
+// handlePowerUpCommands uses a held power-up when its command is typed. A plain h or l moves
+// the paddle as it is held down, only a count makes them a dash.
+func (l *LevelBricksHL) handlePowerUpCommands(now time.Duration) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		l.typed = ""
+	}
+	for _, ch := range globalChars {
+		if l.typed == "" && !startsCommand(ch, bricksCommands) {
+			continue
+		}
+		l.typed += string(ch)
+		cmd, result := parseCommand(l.typed, bricksCommands)
+		switch result {
+		case commandIncomplete:
+			continue
+		case commandInvalid:
+			l.typed = ""
+			continue
+		}
+		l.typed = ""
+		switch cmd.keys {
+		case "w":
+			if l.usePowerUp(PowerUpWiden) {
+				l.resizePaddle(paddlesXWidth * widenFactor)
+				l.widenedUntil = now + widenDuration
+			}
+		case "h", "l":
+			if cmd.count == 0 {
+				continue
+			}
+			if l.usePowerUp(PowerUpDash) {
+				step := float32(cmd.count) * l.paddleWidth
+				if cmd.keys == "h" {
+					step = -step
+				}
+				l.paddlesX = limitToRange(l.paddlesX+step, 0-l.paddleWidth/2, screenWidth-l.paddleWidth/2)
+			}
+		case "y":
+			if len(l.balls) > 0 && l.usePowerUp(PowerUpMultiBall) {
+				l.balls = splitBalls(l.balls)
+			}
+		case "u":
+			if len(l.lost) > 0 && l.usePowerUp(PowerUpRestore) {
+				l.restoreBall()
+			}
+		}
+	}
+}
+
+// usePowerUp takes a held power-up of the kind, or plays the fail sound if none is held
+func (l *LevelBricksHL) usePowerUp(kind PowerUpKind) bool {
+	held, ok := takePowerUp(l.held, kind)
+	if !ok {
+		PlaySound(failOgg)
+		return false
+	}
+	l.held = held
+	return true
+}
+
+// holds returns true if a power-up of the kind is held
+func (l *LevelBricksHL) holds(kind PowerUpKind) bool {
+	return slices.Contains(l.held, kind)
+}
+
+// takePowerUp removes the first power-up of the kind from held
+func takePowerUp(held []PowerUpKind, kind PowerUpKind) ([]PowerUpKind, bool) {
+	i := slices.Index(held, kind)
+	if i < 0 {
+		return held, false
+	}
+	return slices.Delete(held, i, i+1), true
+}
+
+// splitBalls adds a copy of each moving ball heading the other way across the screen
+func splitBalls(balls []Ball) []Ball {
+	for _, b := range balls {
+		if b.dx == 0 && b.dy == 0 {
+			continue
+		}
+		dx := -b.dx
+		if dx == 0 {
+			dx = 1
+		}
+		balls = append(balls, Ball{x: b.x, y: b.y, dx: dx, dy: b.dy})
+	}
+	return balls
+}
+
+// restoreBall puts the last lost ball back in play just above the bottom paddle, heading up
+func (l *LevelBricksHL) restoreBall() {
+	b := l.lost[len(l.lost)-1]
+	l.lost = l.lost[:len(l.lost)-1]
+	b.x = l.paddlesX + l.paddleWidth/2
+	b.y = screenHeight - paddlesXHeight - ballRadius - 1
+	if b.dy > 0 {
+		b.dy = -b.dy
+	}
+	b.dx, b.dy = enforceMinimumSpeed(b.dx, b.dy, l.minimumSpeed)
+	l.balls = append(l.balls, b)
+}
+
+// resizePaddle changes the width of the horizontal paddles, keeping their centers in place
+func (l *LevelBricksHL) resizePaddle(width float32) {
+	l.paddlesX -= (width - l.paddleWidth) / 2
+	l.paddleWidth = width
+}
+
+// drawPowerUps draws the falling letters and the commands of the held power-ups
+func (l *LevelBricksHL) drawPowerUps(screen *ebiten.Image, now time.Duration) {
+	for _, d := range l.drops {
+		vector.DrawFilledRect(screen, d.x-dropSize/2, d.y-dropSize/2, dropSize, dropSize, mediumButter, false)
+		drawBufferString(screen, powerUpKeys[d.kind], int(d.x)-dropSize/2+4, int(d.y)-dropSize/2, 0, 0, darkCoal)
+	}
+	if len(l.held) > 0 {
+		keys := make([]string, len(l.held))
+		for i, kind := range l.held {
+			keys[i] = powerUpKeys[kind]
+		}
+		drawBufferString(screen, "Ready: "+strings.Join(keys, " "), 10,
+			screenHeight-paddlesXHeight-bufferLineHeight-10, 0, 0, bufferText)
+	}
+	if len(l.balls) == 0 && l.restoreBy > 0 {
+		left := (l.restoreBy - now + time.Second - 1) / time.Second
+		drawBufferString(screen, fmt.Sprintf("Type u to undo losing the ball, %ds", left), 10,
+			screenHeight-paddlesXHeight-2*bufferLineHeight-10, 0, 0, bufferText)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTakePowerUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		held   []PowerUpKind
+		kind   PowerUpKind
+		want   []PowerUpKind
+		wantOk bool
+	}{
+		{"None held", nil, PowerUpWiden, nil, false},
+		{"Only one", []PowerUpKind{PowerUpDash}, PowerUpDash, []PowerUpKind{}, true},
+		{"First of two", []PowerUpKind{PowerUpRestore, PowerUpWiden, PowerUpRestore}, PowerUpRestore,
+			[]PowerUpKind{PowerUpWiden, PowerUpRestore}, true},
+		{"Not held", []PowerUpKind{PowerUpDash}, PowerUpMultiBall, []PowerUpKind{PowerUpDash}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := takePowerUp(slices.Clone(tt.held), tt.kind)
+			if ok != tt.wantOk || !slices.Equal(got, tt.want) {
+				t.Errorf("takePowerUp() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSplitBalls(t *testing.T) {
+	tests := []struct {
+		name  string
+		balls []Ball
+		want  []Ball
+	}{
+		{"One ball", []Ball{{10, 20, 2, -3}}, []Ball{{10, 20, 2, -3}, {10, 20, -2, -3}}},
+		{"Straight up", []Ball{{10, 20, 0, -3}}, []Ball{{10, 20, 0, -3}, {10, 20, 1, -3}}},
+		{"Still ball is not copied", []Ball{{10, 20, 0, 0}}, []Ball{{10, 20, 0, 0}}},
+		{"Two balls", []Ball{{1, 1, 1, 1}, {2, 2, -1, 1}},
+			[]Ball{{1, 1, 1, 1}, {2, 2, -1, 1}, {1, 1, -1, 1}, {2, 2, 1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitBalls(slices.Clone(tt.balls)); !slices.Equal(got, tt.want) {
+				t.Errorf("splitBalls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreWaitCostsALife(t *testing.T) {
+	start := time.Second
+	tests := []struct {
+		name      string
+		held      []PowerUpKind
+		at        time.Duration
+		wantLives int
+	}{
+		{"Nothing to restore with", nil, start, 2},
+		{"Waiting for u", []PowerUpKind{PowerUpRestore}, start + restoreWait - tick, 3},
+		{"Too late", []PowerUpKind{PowerUpRestore}, start + restoreWait, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LevelBricksHL{level: LevelIdBricksHL, held: tt.held, scoreboard: NewScoreboard(LevelIdBricksHL)}
+			// the last ball drops past the paddle
+			l.balls = []Ball{{x: screenWidth / 2, y: screenHeight + 2*ballRadius}}
+			l.checkBallLost(start)
+			l.checkBallLost(tt.at)
+			if l.scoreboard.lives != tt.wantLives {
+				t.Errorf("lives = %d, want %d", l.scoreboard.lives, tt.wantLives)
+			}
+			if lostLife := tt.wantLives < 3; lostLife != (len(l.balls) == 1) {
+				t.Errorf("%d balls in play after losing a life is %v", len(l.balls), lostLife)
+			}
+			if lostLife := tt.wantLives < 3; lostLife && len(l.lost) > 0 {
+				t.Errorf("%d balls from the life lost can still be restored", len(l.lost))
+			}
+		})
+	}
+}
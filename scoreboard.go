@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+/*
+ * The lives and score of the arcade levels, bricks, snake and flappy. Points pop up where they
+ * were won and float away. Losing the last life is game over, which the level reports as a
+ * failure so the player is offered a retry or the level select. Scores feed the best score
+ * kept for each level, which is saved so it lasts from one game to the next.
+ */
+
+const (
+	bestScoresKey = "best_scores"          // the best scores are saved under
+	popupDuration = 750 * time.Millisecond // a score popup is shown for
+	popupRise     = 30                     // pixels a score popup floats up
+)
+
+// arcadeLives is the number of lives in each arcade level
+var arcadeLives = map[LevelID]int{
+	LevelIdFlappy:        3,
+	LevelIdBricksHL:      3,
+	LevelIdBricksHJKL:    3,
+	LevelIdSnake:         3,
+	LevelIdInsertMode:    3,
+	LevelIdFlappyGravity: 3,
+	LevelIdSnakeWrap:     3,
+}
+
+// ScorePopup shows the points just won
+type ScorePopup struct {
+	text  string
+	x, y  float32
+	start time.Duration
+}
+
+// Scoreboard is the lives and score of an arcade level
+type Scoreboard struct {
+	lives  int
+	score  int
+	popups []ScorePopup
+}
+
+// NewScoreboard returns a scoreboard with the lives of the level and no points
+func NewScoreboard(id LevelID) Scoreboard {
+	return Scoreboard{lives: arcadeLives[id]}
+}
+
+// Add adds points to the score, popping them up at x, y
+func (s *Scoreboard) Add(points int, x, y float32, now time.Duration) {
+	s.score += points
+	s.popups = append(s.popups, ScorePopup{text: fmt.Sprintf("+%d", points), x: x, y: y, start: now})
+}
+
+// LoseLife takes a life, returning true if there are lives left
+func (s *Scoreboard) LoseLife() bool {
+	s.lives = max(s.lives-1, 0)
+	return s.lives > 0
+}
+
+// GameOver returns true when every life has been lost
+func (s *Scoreboard) GameOver() bool {
+	return s.lives == 0
+}
+
+// Failed reports game over as the reason an arcade level was lost
+func (s *Scoreboard) Failed() (string, bool) {
+	return "Game over, you have no lives left.", s.GameOver()
+}
+
+// Update removes the popups that have been shown for long enough
+func (s *Scoreboard) Update(now time.Duration) {
+	popups := s.popups[:0]
+	for _, p := range s.popups {
+		if now < p.start+popupDuration {
+			popups = append(popups, p)
+		}
+	}
+	s.popups = popups
+}
+
+// Draw shows the score and lives in the top right corner and the popups fading as they rise,
+// in the opaque color clr
+func (s *Scoreboard) Draw(screen *ebiten.Image, now time.Duration, clr color.RGBA) {
+	drawBufferString(screen, fmt.Sprintf("Score %d", s.score), screenWidth-160, 10, 0, 0, clr)
+	drawBufferString(screen, fmt.Sprintf("Lives %d", s.lives), screenWidth-160, 10, 0, 1, clr)
+	for _, p := range s.popups {
+		progress := float32(now-p.start) / float32(popupDuration)
+		faded := color.NRGBA{clr.R, clr.G, clr.B, uint8(0xff * (1 - progress))}
+		drawBufferString(screen, p.text, int(p.x), int(p.y-progress*popupRise), 0, 0, faded)
+	}
+}
+
+// BestScores is the highest score won in each level
+type BestScores map[LevelID]int
+
+// Record keeps the score if it beats the best for the level, returning true if it does.
+// A level not yet scored has a best of 0.
+func (b BestScores) Record(id LevelID, score int) bool {
+	if score <= b[id] {
+		return false
+	}
+	b[id] = score
+	return true
+}
+
+// loadBestScores returns the saved best scores, none if they have not been saved or can't be read
+func loadBestScores() BestScores {
+	b := BestScores{}
+	data, err := loadSaved(bestScoresKey)
+	if err != nil {
+		log.Println("Error loading best scores:", err)
+		return b
+	}
+	if data == nil {
+		return b
+	}
+	b, err = decodeBestScores(data)
+	if err != nil {
+		log.Println("Error reading best scores:", err)
+		return BestScores{}
+	}
+	return b
+}
+
+// Save saves the best scores for the next game
+func (b BestScores) Save() {
+	data, err := b.encode()
+	if err == nil {
+		err = save(bestScoresKey, data)
+	}
+	if err != nil {
+		log.Println("Error saving best scores:", err)
+	}
+}
+
+// encode returns the best scores as JSON, keyed by level name
+func (b BestScores) encode() ([]byte, error) {
+	named := make(map[string]int, len(b))
+	for id, score := range b {
+		named[levelNames[id]] = score
+	}
+	return json.Marshal(named)
+}
+
+// decodeBestScores reads best scores saved by encode. Levels no longer in the game are dropped.
+func decodeBestScores(data []byte) (BestScores, error) {
+	var named map[string]int
+	if err := json.Unmarshal(data, &named); err != nil {
+		return nil, err
+	}
+	b := BestScores{}
+	for id, name := range levelNames {
+		if score, ok := named[name]; ok {
+			b[id] = score
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestScoreboardLives(t *testing.T) {
+	s := Scoreboard{lives: 2}
+	if !s.LoseLife() || s.GameOver() {
+		t.Fatalf("after the first of 2 lives, lives = %d, game over = %v", s.lives, s.GameOver())
+	}
+	if s.LoseLife() || !s.GameOver() {
+		t.Fatalf("after the last life, lives = %d, game over = %v", s.lives, s.GameOver())
+	}
+	if s.LoseLife() || s.lives != 0 {
+		t.Errorf("losing a life after game over left %d lives", s.lives)
+	}
+	if _, failed := s.Failed(); !failed {
+		t.Errorf("Failed() = false after game over")
+	}
+}
+
+func TestScoreboardPopups(t *testing.T) {
+	var s Scoreboard
+	s.Add(10, 0, 0, 100*time.Millisecond)
+	s.Add(25, 0, 0, 120*time.Millisecond)
+	if s.score != 35 {
+		t.Errorf("score = %d, want 35", s.score)
+	}
+	tests := []struct {
+		now  time.Duration
+		want int
+	}{
+		{100*time.Millisecond + popupDuration - 1, 2},
+		{100*time.Millisecond + popupDuration, 1},
+		{120*time.Millisecond + popupDuration, 0},
+	}
+	for _, tt := range tests {
+		s.Update(tt.now)
+		if len(s.popups) != tt.want {
+			t.Errorf("at %v there are %d popups, want %d", tt.now, len(s.popups), tt.want)
+		}
+	}
+}
+
+func TestBestScoresRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    LevelID
+		score int
+		want  bool
+		best  int
+	}{
+		{"First score", LevelIdSnake, 30, true, 30},
+		{"Lower score", LevelIdSnake, 20, false, 30},
+		{"Equal score", LevelIdSnake, 30, false, 30},
+		{"Higher score", LevelIdSnake, 40, true, 40},
+		{"No points", LevelIdFlappy, 0, false, 0},
+	}
+	b := BestScores{}
+	for _, tt := range tests {
+		if got := b.Record(tt.id, tt.score); got != tt.want || b[tt.id] != tt.best {
+			t.Errorf("%s: Record(%d) = %v with best %d, want %v with best %d", tt.name, tt.score, got, b[tt.id], tt.want, tt.best)
+		}
+	}
+}
+
+func TestBestScoresSavedByName(t *testing.T) {
+	names := map[string]bool{}
+	for id := LevelID(LevelIdFlappy); id <= LevelIdSnakeWrap; id++ {
+		name, ok := levelNames[id]
+		if !ok || names[name] {
+			t.Errorf("level %d has no name of its own, %q", id, name)
+		}
+		names[name] = true
+	}
+
+	b := BestScores{LevelIdSnake: 30, LevelIdSnakeWrap: 50}
+	data, err := b.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeBestScores(data)
+	if err != nil || !maps.Equal(got, b) {
+		t.Errorf("decodeBestScores(%s) = %v, %v, want %v", data, got, err, b)
+	}
+	if got, err := decodeBestScores([]byte(`{"snake": 30, "a_level_since_removed": 10}`)); err != nil || !maps.Equal(got, BestScores{LevelIdSnake: 30}) {
+		t.Errorf("decodeBestScores() = %v, %v, want only the snake score", got, err)
+	}
+}
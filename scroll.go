@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/*
+ * LevelScroll provides practice of the scroll commands on a tower of gems too tall for the
+ * screen. The player scrolls with Ctrl-D, Ctrl-U, Ctrl-F and Ctrl-B to find the gold row,
+ * moves the cursor onto it and places it at the top, middle or bottom of the screen with
+ * zt, zz and zb.
+ */
+
+const (
+	numScrollObjectives = 6
+	scrollCellSize      = gemCellSize
+	scrollColumns       = 8
+	scrollLeft          = 100
+	scrollNumGems       = 5
+	scrollPanelLeft     = 540
+	scrollRows          = 60
+	scrollTop           = (screenHeight - scrollVisibleRows*scrollCellSize) / 2
+	scrollVisibleRows   = 11
+)
+
+var colorScrollTarget = lightGold
+
+var scrollCommands = []commandSpec{
+	{keys: "j"}, {keys: "k"}, {keys: "G"}, {keys: "gg"},
+	{keys: "zt"}, {keys: "zz"}, {keys: "zb"},
+}
+
+// scrollPosition is where on the screen an objective wants the gold row
+type scrollPosition int
+
+const (
+	scrollToTop scrollPosition = iota
+	scrollToCenter
+	scrollToBottom
+)
+
+type scrollObjective struct {
+	row      int
+	position scrollPosition
+}
+
+type LevelScroll struct {
+	cursor     int // the row of the cursor in the tower
+	gemImages  []*ebiten.Image
+	gems       Grid[int]
+	level      LevelID
+	objective  int
+	objectives []scrollObjective
+	typed      string
+	view       Viewport
+}
+
+func (l *LevelScroll) Draw(screen *ebiten.Image, now time.Duration) {
+	screen.Fill(darkCoal)
+	o, haveObjective := l.currentObjective()
+
+	cursorColors := [2]color.Color{redCursor, whiteCursor}
+	blink := blinkPhase(now, blinkInterval)
+	for row := l.view.FirstVisibleRow(); row <= l.view.LastVisibleRow(); row++ {
+		pos := l.view.CellToScreen(Coord{0, row})
+		switch {
+		case row == l.cursor:
+			vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y),
+				scrollColumns*scrollCellSize, scrollCellSize, cursorColors[blink], false)
+		case haveObjective && row == o.row:
+			vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y),
+				scrollColumns*scrollCellSize, scrollCellSize, colorScrollTarget, false)
+		}
+		for x := range scrollColumns {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(gemScale, gemScale)
+			p := l.view.CellToScreen(Coord{x, row})
+			op.GeoM.Translate(float64(p.x), float64(p.y))
+			screen.DrawImage(l.gemImages[l.gems.Get(Coord{x, row})], op)
+		}
+		drawBufferString(screen, lineNumber(row+1), scrollLeft-4*bufferCharWidth, pos.y+12, 0, 0, bufferLineNums)
+	}
+
+	// the side panel shows the objective and where to find the gold row
+	drawBufferString(screen, fmt.Sprintf("Row %d of %d", l.cursor+1, scrollRows), scrollPanelLeft, scrollTop, 0, 0, bufferDimText)
+	if haveObjective {
+		where := [...]string{"top", "middle", "bottom"}[o.position]
+		drawBufferString(screen, "Put the gold row", scrollPanelLeft, scrollTop, 0, 2, bufferText)
+		drawBufferString(screen, "at the "+where, scrollPanelLeft, scrollTop, 0, 3, bufferText)
+		drawBufferString(screen, "of the screen", scrollPanelLeft, scrollTop, 0, 4, bufferText)
+		switch {
+		case o.row < l.view.FirstVisibleRow():
+			drawBufferString(screen, "Gold row is above", scrollPanelLeft, scrollTop, 0, 6, darkButter)
+		case o.row > l.view.LastVisibleRow():
+			drawBufferString(screen, "Gold row is below", scrollPanelLeft, scrollTop, 0, 6, darkButter)
+		}
+		drawBufferString(screen, fmt.Sprintf("%d to go", len(l.objectives)-l.objective),
+			scrollPanelLeft, scrollTop, 0, 8, bufferDimText)
+	}
+	drawBufferString(screen, l.typed, scrollPanelLeft, screenHeight-bufferLineHeight-10, 0, 0, bufferText)
+}
+
+func (l *LevelScroll) Initialize(id LevelID) {
+	l.level = id
+	l.cursor = 0
+	l.typed = ""
+	l.view = NewViewport(scrollColumns, scrollRows, scrollCellSize, scrollLeft, scrollTop, scrollVisibleRows)
+	l.gems = NewGrid[int](scrollColumns, scrollRows)
+	for y := range scrollRows {
+		for x := range scrollColumns {
+			l.gems.Set(Coord{x, y}, rng.Intn(scrollNumGems))
+		}
+	}
+	l.objective = 0
+	l.objectives = newScrollObjectives()
+	if len(l.gemImages) == 0 {
+		for i := range scrollNumGems {
+			l.gemImages = append(l.gemImages, loadImage("resources/Gem "+strconv.Itoa(i+1)+".png"))
+		}
+	}
+}
+
+func (l *LevelScroll) Update(clock Clock) (bool, error) {
+	if isCheatKeyPressed() {
+		return true, nil
+	}
+
+	halfPage := scrollVisibleRows / 2
+	page := scrollVisibleRows - 2 // a page scroll keeps two rows of context
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		switch {
+		case inpututil.IsKeyJustPressed(ebiten.KeyD):
+			l.view.ScrollBy(halfPage)
+			l.moveCursor(l.cursor + halfPage)
+		case inpututil.IsKeyJustPressed(ebiten.KeyU):
+			l.view.ScrollBy(-halfPage)
+			l.moveCursor(l.cursor - halfPage)
+		case inpututil.IsKeyJustPressed(ebiten.KeyF):
+			l.view.ScrollBy(page)
+			l.moveCursor(max(l.cursor, l.view.FirstVisibleRow()))
+		case inpututil.IsKeyJustPressed(ebiten.KeyB):
+			l.view.ScrollBy(-page)
+			l.moveCursor(min(l.cursor, l.view.LastVisibleRow()))
+		}
+	} else {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			l.typed = ""
+		}
+		for _, ch := range globalChars {
+			l.typed += string(ch)
+			cmd, result := parseCommand(l.typed, scrollCommands)
+			switch result {
+			case commandComplete:
+				l.execute(cmd)
+				l.typed = ""
+			case commandInvalid:
+				PlaySound(failOgg)
+				l.typed = ""
+			}
+		}
+	}
+	clearKeystrokes()
+
+	if o, ok := l.currentObjective(); ok && l.isObjectiveMet(o) {
+		PlaySound(tripleOgg)
+		l.objective++
+	}
+	return l.objective >= len(l.objectives), nil
+}
+
+func (l *LevelScroll) currentObjective() (scrollObjective, bool) {
+	if l.objective >= len(l.objectives) {
+		return scrollObjective{}, false
+	}
+	return l.objectives[l.objective], true
+}
+
+func (l *LevelScroll) execute(cmd viCommand) {
+	switch cmd.keys {
+	case "j":
+		l.moveCursor(l.cursor + cmd.Count())
+	case "k":
+		l.moveCursor(l.cursor - cmd.Count())
+	case "G":
+		if cmd.count > 0 {
+			l.moveCursor(cmd.count - 1)
+		} else {
+			l.moveCursor(scrollRows - 1)
+		}
+	case "gg":
+		l.moveCursor(cmd.Count() - 1)
+	case "zt":
+		l.view.ShowRowAtTop(l.cursor)
+	case "zz":
+		l.view.ShowRowAtCenter(l.cursor)
+	case "zb":
+		l.view.ShowRowAtBottom(l.cursor)
+	}
+}
+
+func (l *LevelScroll) isObjectiveMet(o scrollObjective) bool {
+	if l.cursor != o.row {
+		return false
+	}
+	switch o.position {
+	case scrollToTop:
+		return l.view.FirstVisibleRow() == o.row
+	case scrollToCenter:
+		return l.view.FirstVisibleRow()+scrollVisibleRows/2 == o.row
+	case scrollToBottom:
+		return l.view.LastVisibleRow() == o.row
+	}
+	return false
+}
+
+// moveCursor moves the cursor to row, scrolling if needed to keep it on the screen
+func (l *LevelScroll) moveCursor(row int) {
+	l.cursor = limitToRange(row, 0, scrollRows-1)
+	l.view.KeepRowVisible(l.cursor)
+}
+
+// newScrollObjectives places the gold row out of sight, far enough from the ends of the tower
+// that it can be scrolled to any position on the screen.
+func newScrollObjectives() []scrollObjective {
+	objectives := make([]scrollObjective, numScrollObjectives)
+	last := 0
+	for i := range objectives {
+		row := last
+		for abs(row-last) < scrollVisibleRows {
+			row = scrollVisibleRows + rng.Intn(scrollRows-2*scrollVisibleRows)
+		}
+		objectives[i] = scrollObjective{row: row, position: scrollPosition(i % 3)}
+		last = row
+	}
+	return objectives
+}
@@ -1,17 +1,32 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
-	"math/rand"
+	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 /*
- * LevelSnake implements two levels. The first (LevelIdSnake) provides the play practice of the
+ * LevelSnake implements four levels. The first (LevelIdSnake) provides the play practice of the
  * navigation keys, h,j,k,l. The second (LevelIDInsertMode) provides the play practice of the navigation
- * and entering and exiting insert mode.
+ * and entering and exiting insert mode. The third (LevelIdSnakeDuel) is a versus mode for two
+ * players sharing the keyboard, player one steers with h,j,k,l and player two with the arrow keys.
+ * The fourth (LevelIdSnakeWrap) is played from the level select and wraps around the edges.
+ *
+ * The walls of each level are an ASCII map under assets/levels, # for a wall and . for an empty
+ * square. A level may let the snake wrap around the edges of the screen. The snake speeds up as
+ * it grows. Besides apples there is bonus food that disappears if it is not eaten in time, and
+ * poison that shrinks the snake.
+ *
+ * In the duel the snakes share the food. A snake crashing into a wall or either snake loses the
+ * round, both lose when they meet head on. Growing to the length for a win also wins the round.
  */
 type Direction int
 
@@ -22,110 +37,400 @@ const (
 	west
 )
 
+// FoodKind is what eating a food does to the snake
+type FoodKind int
+
+const (
+	FoodApple  FoodKind = iota
+	FoodBonus           // worth more, but disappears after a while
+	FoodPoison          // shrinks the snake
+)
+
+const (
+	foodPoints    = 10 // for each apple eaten
+	bonusPoints   = 30 // for each bonus food eaten
+	bonusLifetime = 6 * time.Second
+	poisonShrink  = 2 // squares lost by the snake for eating poison
+	roundsToWin   = 3 // rounds won to win a duel
+)
+
 type Snake struct {
 	body      []Coord
 	direction Direction
+	color     color.RGBA
+	keys      []turnKey // keys that turn the snake, nil for h, j, k, l typed as commands
+	turns     TurnQueue
+	typed     string // the characters of a turn command being typed
+}
+
+// turnKey is a key that turns a snake
+type turnKey struct {
+	key       ebiten.Key
+	direction Direction
+}
+
+// snakeStart is where a snake starts and the direction it heads
+type snakeStart struct {
+	pos       Coord
+	direction Direction
+}
+
+// Food is something for the snake to eat
+type Food struct {
+	pos     Coord
+	kind    FoodKind
+	expires time.Duration // game time a bonus food disappears
+}
+
+// snakeLevelConfig is the map, edges, players, speed, food and goal of a snake level
+type snakeLevelConfig struct {
+	mapFile       string
+	size          int           // size of each square in the grid
+	starts        []snakeStart  // a start for each player
+	lengthForWin  int           // the snake wins when it grows this long
+	wrap          bool          // the snake leaves one edge of the screen and comes back on the other
+	startInterval time.Duration // between moves when the snake is one square long
+	minInterval   time.Duration // the fastest the snake moves, between moves
+	intervalStep  time.Duration // taken off the interval for each square the snake grows
+	bonusChance   float64       // chance of bonus food appearing when an apple is eaten
+	poisons       int           // poison on the map at once
+}
+
+var snakeLevels = map[LevelID]snakeLevelConfig{
+	LevelIdSnake: {
+		mapFile:       "assets/levels/snake.txt",
+		size:          40,
+		starts:        []snakeStart{{Coord{5, 7}, east}},
+		lengthForWin:  22,
+		startInterval: 500 * time.Millisecond,
+		minInterval:   200 * time.Millisecond,
+		intervalStep:  16 * time.Millisecond,
+		bonusChance:   0.25,
+	},
+	LevelIdInsertMode: {
+		mapFile:       "assets/levels/snake_insert.txt",
+		size:          40,
+		starts:        []snakeStart{{Coord{5, 7}, east}},
+		lengthForWin:  19,
+		startInterval: 500 * time.Millisecond,
+		minInterval:   250 * time.Millisecond,
+		intervalStep:  16 * time.Millisecond,
+		bonusChance:   0.25,
+		poisons:       2,
+	},
+	LevelIdSnakeDuel: {
+		mapFile:       "assets/levels/snake_duel.txt",
+		size:          40,
+		starts:        []snakeStart{{Coord{3, 3}, east}, {Coord{16, 11}, west}},
+		lengthForWin:  12,
+		startInterval: 330 * time.Millisecond,
+		minInterval:   200 * time.Millisecond,
+		intervalStep:  16 * time.Millisecond,
+		bonusChance:   0.25,
+	},
+	LevelIdSnakeWrap: {
+		mapFile:       "assets/levels/snake_wrap.txt",
+		size:          40,
+		starts:        []snakeStart{{Coord{5, 7}, east}},
+		lengthForWin:  22,
+		wrap:          true,
+		startInterval: 400 * time.Millisecond,
+		minInterval:   170 * time.Millisecond,
+		intervalStep:  16 * time.Millisecond,
+		bonusChance:   0.3,
+		poisons:       1,
+	},
 }
 
 type LevelSnake struct {
-	level  LevelID
-	food   Coord
-	score  int
-	snake  *Snake
-	viMode VIMode
+	config     snakeLevelConfig
+	level      LevelID
+	foods      []Food
+	nextMove   time.Duration // game time the snakes next move
+	particles  ParticleSystem
+	scoreboard Scoreboard
+	snakes     []*Snake // player one first
+	viMode     VIMode
+	walls      Grid[bool]
+	winner     int   // player who won the duel, -1 while it goes on
+	wins       []int // rounds won by each player in a duel
 }
 
 var (
-	gridWidth    = screenWidth / size
-	gridHeight   = screenHeight / size
-	snakeColor   = color.RGBA{R: 0x20, G: 0xFF, B: 0x20, A: 0xFF}
-	foodColor    = color.RGBA{0xcc, 0x00, 0x00, 0xa0} // mediumScarletRed
-	size         = 40                                 // size of each square in the grid
-	lengthForWin = 22
+	snakeColor  = color.RGBA{R: 0x20, G: 0xFF, B: 0x20, A: 0xFF}
+	insertColor = color.RGBA{R: 0xCC, G: 0x20, B: 0x40, A: 0xFF} // the snake can eat in insert mode
+	foodColor   = color.RGBA{0xcc, 0x00, 0x00, 0xa0}             // mediumScarletRed
 )
 
-func (l *LevelSnake) Draw(screen *ebiten.Image, frameCount int) {
-	screen.Fill(darkCoal)
-	sc := snakeColor
-	var green = sc.G
-	var red = sc.R
-
-	if l.level == LevelIdInsertMode && l.viMode == InsertMode {
-		// show different color if able to eat food in insert level
-		sc = color.RGBA{R: 0xCC, G: 0x20, B: 0x40, A: 0xFF}
-		red = sc.R - (0x08 * uint8(len(l.snake.body)))
-		green = sc.G
-	} else {
-		green = sc.G - (0x08 * uint8(len(l.snake.body)))
+// playerColors is the color of each player's snake
+var playerColors = []color.RGBA{snakeColor, {R: 0x20, G: 0x60, B: 0xFF, A: 0xFF}}
+
+// playerKeys is the keys of each player, player one types h, j, k, l
+var playerKeys = [][]turnKey{
+	nil,
+	{
+		{ebiten.KeyArrowLeft, west},
+		{ebiten.KeyArrowDown, south},
+		{ebiten.KeyArrowUp, north},
+		{ebiten.KeyArrowRight, east},
+	},
+}
+
+// playerNames is how each player is named in the duel
+var playerNames = []string{"Player one", "Player two"}
+
+// foodColors is the color each kind of food is drawn in
+var foodColors = map[FoodKind]color.Color{
+	FoodApple:  foodColor,
+	FoodBonus:  mediumButter,
+	FoodPoison: mediumPlum,
+}
+
+// columns returns the width of the grid in squares
+func (c snakeLevelConfig) columns() int {
+	return screenWidth / c.size
+}
+
+// rows returns the height of the grid in squares
+func (c snakeLevelConfig) rows() int {
+	return screenHeight / c.size
+}
+
+// interval returns the time between moves of a snake of length squares
+func (c snakeLevelConfig) interval(length int) time.Duration {
+	return max(c.startInterval-c.intervalStep*time.Duration(length-1), c.minInterval)
+}
+
+// duel returns true for a level played by more than one player
+func (c snakeLevelConfig) duel() bool {
+	return len(c.starts) > 1
+}
+
+// parseSnakeMap reads the walls of a snake map, which must be columns by rows squares
+func parseSnakeMap(text string, columns, rows int) (Grid[bool], error) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) != rows {
+		return nil, fmt.Errorf("snake map has %d lines, want %d", len(lines), rows)
+	}
+	walls := NewGridOfBools(columns, rows)
+	for y, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if len(line) != columns {
+			return nil, fmt.Errorf("line %d: %d squares, want %d", y+1, len(line), columns)
+		}
+		for x, ch := range line {
+			switch ch {
+			case '#':
+				walls.Set(Coord{x, y}, true)
+			case '.':
+			default:
+				return nil, fmt.Errorf("line %d: unknown square %q", y+1, ch)
+			}
+		}
 	}
+	return walls, nil
+}
+
+// loadSnakeMap loads the walls of a level from the embedded assets
+func loadSnakeMap(c snakeLevelConfig) Grid[bool] {
+	data, err := embeddedAssets.ReadFile(c.mapFile)
+	if err != nil {
+		log.Fatalf("Error loading snake map: %v", err)
+	}
+	walls, err := parseSnakeMap(string(data), c.columns(), c.rows())
+	if err != nil {
+		log.Fatalf("Error in snake map %s: %v", c.mapFile, err)
+	}
+	return walls
+}
+
+// shadeSegment returns the color of segment i, counted from the tail, of a snake of length
+// squares. The brightest channel of clr is darkened toward the tail.
+func shadeSegment(clr color.RGBA, length, i int) color.RGBA {
+	shade := uint8(min(0x08*(length-i), 0xa0))
+	switch max(clr.R, clr.G, clr.B) {
+	case clr.G:
+		clr.G -= shade
+	case clr.R:
+		clr.R -= shade
+	default:
+		clr.B -= shade
+	}
+	return clr
+}
+
+func (l *LevelSnake) Draw(screen *ebiten.Image, now time.Duration) {
+	screen.Fill(darkCoal)
+	size := l.config.size
 
-	// Draw the snake from tail to head
-	for _, p := range l.snake.body {
-		sc = color.RGBA{red, green, sc.B, sc.A}
-		vector.DrawFilledRect(screen, float32(p.x*size), float32(p.y*size), float32(size), float32(size), sc, false)
+	// Draw the walls
+	l.walls.ForEach(func(p Coord, wall bool) {
+		if wall {
+			vector.DrawFilledRect(screen, float32(p.x*size), float32(p.y*size), float32(size), float32(size), mediumCoal, false)
+		}
+	})
+
+	// Draw each snake from tail to head
+	for _, s := range l.snakes {
+		sc := s.color
 		if l.level == LevelIdInsertMode && l.viMode == InsertMode {
-			red += 0x08
-		} else {
-			green += 0x08
+			// show different color if able to eat food in insert level
+			sc = insertColor
+		}
+		for i, p := range s.body {
+			vector.DrawFilledRect(screen, float32(p.x*size), float32(p.y*size), float32(size), float32(size),
+				shadeSegment(sc, len(s.body), i), false)
 		}
 	}
 
-	// Draw the food
-	vector.DrawFilledRect(screen, float32(l.food.x*size), float32(l.food.y*size), float32(size), float32(size), foodColor, false)
+	// Draw the food, bonus food blinks in its last two seconds
+	for _, f := range l.foods {
+		if f.kind == FoodBonus && f.expires-now < 2*time.Second && blinkPhase(now, time.Second/6) == 0 {
+			continue
+		}
+		vector.DrawFilledRect(screen, float32(f.pos.x*size), float32(f.pos.y*size), float32(size), float32(size), foodColors[f.kind], false)
+	}
+	l.particles.Draw(screen)
 
-	// Draw the score
-	// ebitenutil.DrawString(screen, fmt.Sprintf("Score: %d", l.score), 10, 10)
+	if l.config.duel() {
+		for i, s := range l.snakes {
+			drawBufferString(screen, fmt.Sprintf("%s %d", playerNames[i], l.wins[i]), screenWidth-220, 10, 0, i, s.color)
+		}
+		return
+	}
+	l.scoreboard.Draw(screen, now, bufferText)
 }
 
 func (l *LevelSnake) Initialize(id LevelID) {
+	l.level = id
+	l.config = snakeLevels[id]
+	l.walls = loadSnakeMap(l.config)
+	l.scoreboard = NewScoreboard(id)
+	l.wins = make([]int, len(l.config.starts))
+	l.winner = -1
+	l.particles.Clear()
+	l.resetSnakes()
+}
+
+// resetSnakes starts new snakes, as the level starts and after a snake crashes
+func (l *LevelSnake) resetSnakes() {
 	l.viMode = NormalMode
-	l.snake = &Snake{
-		body:      []Coord{{x: 5, y: gridHeight / 2}},
-		direction: east,
+	l.snakes = make([]*Snake, len(l.config.starts))
+	for i, start := range l.config.starts {
+		l.snakes[i] = &Snake{
+			body:      []Coord{start.pos},
+			direction: start.direction,
+			color:     playerColors[i],
+			keys:      playerKeys[i],
+		}
 	}
-	l.level = id
-	l.food = l.generateFood(Coord{x: 0, y: 0})
-	l.score = 0
-	if l.level == LevelIdInsertMode {
-		lengthForWin -= 3
+	l.nextMove = 0
+	l.foods = nil
+	l.addFood(FoodApple, 0)
+	for range l.config.poisons {
+		l.addFood(FoodPoison, 0)
 	}
 }
 
-func (l *LevelSnake) Update(frameCount int) (bool, error) {
-	if isCheatKeyPressed() {
-		return true, nil
+// crash takes a life and starts a new snake if there are lives left
+func (l *LevelSnake) crash() {
+	PlaySound(failOgg)
+	if l.scoreboard.LoseLife() {
+		l.resetSnakes()
 	}
-	// Handle keystrokes
-	canTurn := l.level == LevelIdSnake || (l.level == LevelIdInsertMode && l.viMode == NormalMode)
-	dir := l.snake.direction
-	if ebiten.IsKeyPressed(ebiten.KeyH) {
-		if l.snake.direction != east && canTurn {
-			dir = west
-		} else {
-			PlaySound(failOgg)
+}
+
+// endRound gives a round of a duel to each snake that is not a loser, unless they all are, and
+// starts the next round. The first player to win enough rounds wins the duel.
+func (l *LevelSnake) endRound(losers []*Snake) {
+	if len(losers) < len(l.snakes) {
+		PlaySound(tripleOgg)
+		for i, s := range l.snakes {
+			if !slices.Contains(losers, s) {
+				l.wins[i]++
+			}
 		}
+	} else {
+		// nobody wins a round that every snake loses
+		PlaySound(failOgg)
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyK) {
-		if l.snake.direction != south && canTurn {
-			dir = north
-		} else {
-			PlaySound(failOgg)
+	l.resetSnakes()
+	for i, wins := range l.wins {
+		if wins >= roundsToWin && l.winner < 0 {
+			l.winner = i
 		}
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyJ) {
-		if l.snake.direction != north && canTurn {
-			dir = south
-		} else {
-			PlaySound(failOgg)
+}
+
+// Failed returns true when the last life is lost. A duel always ends with a winner, it is not failed.
+func (l *LevelSnake) Failed() (string, bool) {
+	if l.config.duel() {
+		return "", false
+	}
+	return l.scoreboard.Failed()
+}
+
+// Winner returns the player who won a duel, empty while it goes on and for a level of one player
+func (l *LevelSnake) Winner() string {
+	if l.winner < 0 {
+		return ""
+	}
+	return playerNames[l.winner]
+}
+
+// Score returns the points for the food eaten
+func (l *LevelSnake) Score() int {
+	return l.scoreboard.score
+}
+
+func (l *LevelSnake) Update(clock Clock) (bool, error) {
+	if isCheatKeyPressed() {
+		return true, nil
+	}
+	if _, failed := l.Failed(); failed {
+		return false, nil
+	}
+	now := clock.Now()
+	l.scoreboard.Update(now)
+	l.particles.Update(clock.Step())
+	l.handleTurnCommands()
+
+	l.expireFood(now)
+	for _, s := range l.snakes {
+		if turn, ok := s.turns.Peek(); ok && turn.count > 0 {
+			// a count dashes the snake straight away
+			s.turns.Pop()
+			s.direction = turn.direction
+			for range turn.count {
+				if !l.moveSnakes([]*Snake{s}, now) {
+					break
+				}
+			}
+			l.checkRoundWon()
+			return l.gameIsWon(), nil
 		}
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyL) {
-		if l.snake.direction != west && canTurn {
-			dir = east
-		} else {
-			PlaySound(failOgg)
+	if now >= l.nextMove {
+		// each snake takes one queued turn each move, and they move faster as the longest grows
+		longest := 0
+		for _, s := range l.snakes {
+			if turn, ok := s.turns.Pop(); ok {
+				s.direction = turn.direction
+			}
+			longest = max(longest, len(s.body))
 		}
+		l.nextMove = now + l.config.interval(longest)
+		l.moveSnakes(l.snakes, now)
 	}
+	l.checkRoundWon()
+
+	// continue level until snake dies
+	return l.gameIsWon(), nil
+}
+
+// handleTurnCommands queues the turns typed this frame. Turns are refused with the fail sound
+// when they would reverse a snake, or in insert mode on the insert level.
+func (l *LevelSnake) handleTurnCommands() {
 	if l.level == LevelIdInsertMode {
 		if ebiten.IsKeyPressed(ebiten.KeyI) {
 			l.viMode = InsertMode
@@ -134,84 +439,220 @@ func (l *LevelSnake) Update(frameCount int) (bool, error) {
 			l.viMode = NormalMode
 		}
 	}
-	l.snake.direction = dir
-
-	if frameCount%30 == 0 {
-		// Only move the snake every 30 frames
-		// Update the snake's position
-		head := l.snake.body[len(l.snake.body)-1]
-
-		switch l.snake.direction {
-		case north:
-			head.y -= 1
-		case south:
-			head.y += 1
-		case west:
-			head.x -= 1
-		case east:
-			head.x += 1
-		}
-
-		// Check if the snake has collided with the food
-		canEat := l.level == LevelIdSnake || (l.level == LevelIdInsertMode && l.viMode == InsertMode)
-		if head == l.food && canEat {
-			l.food = l.generateFood(head)
-			l.score++
-			if l.score == lengthForWin {
-				PlaySound(winOgg)
-			} else {
-				PlaySound(tripleOgg)
+	canTurn := l.level != LevelIdInsertMode || l.viMode == NormalMode
+	for _, s := range l.snakes {
+		if s.keys != nil {
+			for _, k := range s.keys {
+				if inpututil.IsKeyJustPressed(k.key) && !s.turns.Push(Turn{direction: k.direction}, s.direction) {
+					PlaySound(failOgg)
+				}
 			}
-		} else {
-			// Remove the tail
-			l.snake.body = l.snake.body[1:]
-
-			// Check if the snake has collided with the boundaries or itself
-			if head.x < 0 || head.x >= gridWidth || head.y < 0 || head.y >= gridHeight {
-				l.Initialize(l.level)
+			continue
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			s.typed = ""
+		}
+		for _, ch := range globalChars {
+			if s.typed == "" && !startsCommand(ch, snakeCommands) {
+				continue
+			}
+			s.typed += string(ch)
+			cmd, result := parseCommand(s.typed, snakeCommands)
+			switch result {
+			case commandIncomplete:
+				continue
+			case commandInvalid:
+				s.typed = ""
+				continue
+			}
+			s.typed = ""
+			if !canTurn || !s.turns.Push(Turn{direction: snakeDirections[cmd.keys], count: cmd.count}, s.direction) {
 				PlaySound(failOgg)
-				return false, nil
 			}
-			for i := 1; i < len(l.snake.body); i++ {
-				if head == l.snake.body[i] {
-					l.Initialize(l.level)
-					PlaySound(failOgg)
-					return false, nil
+		}
+	}
+}
+
+// nextHead returns the square the snake moves its head onto
+func (l *LevelSnake) nextHead(s *Snake) Coord {
+	head := s.body[len(s.body)-1]
+	switch s.direction {
+	case north:
+		head.y -= 1
+	case south:
+		head.y += 1
+	case west:
+		head.x -= 1
+	case east:
+		head.x += 1
+	}
+	if l.config.wrap {
+		head.x = (head.x + l.config.columns()) % l.config.columns()
+		head.y = (head.y + l.config.rows()) % l.config.rows()
+	}
+	return head
+}
+
+// moveSnakes moves the snakes a square at the same time, eating any food they move onto. A snake
+// crashes into walls, any snake and the edges of a level that does not wrap, and snakes moving
+// onto the same square meet head on. Returns false if a snake crashed.
+func (l *LevelSnake) moveSnakes(movers []*Snake, now time.Duration) bool {
+	heads := make([]Coord, len(movers))
+	for i, s := range movers {
+		heads[i] = l.nextHead(s)
+	}
+
+	canEat := l.level != LevelIdInsertMode || l.viMode == InsertMode
+	for i, s := range movers {
+		head := heads[i]
+		// Check if the snake has collided with food
+		grow := false
+		if f := l.foodAt(head); f >= 0 && canEat {
+			food := l.foods[f]
+			l.foods = slices.Delete(l.foods, f, f+1)
+			size := float64(l.config.size)
+			l.particles.Emit("eat", (float64(head.x)+0.5)*size, (float64(head.y)+0.5)*size, foodColors[food.kind])
+			switch food.kind {
+			case FoodApple:
+				grow = true
+				l.addPoints(foodPoints, head, now)
+				l.addFood(FoodApple, now)
+				if rng.Float64() < l.config.bonusChance {
+					l.addFood(FoodBonus, now)
 				}
+			case FoodBonus:
+				grow = true
+				l.addPoints(bonusPoints, head, now)
+			case FoodPoison:
+				// keep at least the head
+				s.body = s.body[min(poisonShrink, len(s.body)-1):]
+				l.addFood(FoodPoison, now)
+				PlaySound(failOgg)
+			}
+			// the win sound is played by gameIsWon
+			if grow && len(s.body)+1 < l.config.lengthForWin {
+				PlaySound(tripleOgg)
 			}
 		}
+		if !grow {
+			// Remove the tail
+			s.body = s.body[1:]
+		}
+	}
+
+	// Check if the snakes have collided with the boundaries, a wall, a snake or each other
+	columns, rows := l.config.columns(), l.config.rows()
+	var crashed []*Snake
+	for i, s := range movers {
+		head := heads[i]
+		hit := head.x < 0 || head.x >= columns || head.y < 0 || head.y >= rows || l.walls.Get(head)
+		for _, other := range l.snakes {
+			hit = hit || slices.Contains(other.body, head)
+		}
+		for j := range movers {
+			hit = hit || (j != i && heads[j] == head)
+		}
+		if hit {
+			crashed = append(crashed, s)
+		}
+	}
+	if len(crashed) > 0 {
+		if l.config.duel() {
+			l.endRound(crashed)
+		} else {
+			l.crash()
+		}
+		return false
+	}
 
-		// Update the snake's body
-		l.snake.body = append(l.snake.body, head)
+	// Update the snakes' bodies
+	for i, s := range movers {
+		s.body = append(s.body, heads[i])
 	}
+	return true
+}
 
-	// continue level until snake dies
-	return l.gameIsWon(), nil
+// addPoints scores the food eaten at p, the duel is not scored
+func (l *LevelSnake) addPoints(points int, p Coord, now time.Duration) {
+	if !l.config.duel() {
+		l.scoreboard.Add(points, float32(p.x*l.config.size), float32(p.y*l.config.size), now)
+	}
 }
 
+// expireFood removes the bonus food that was not eaten in time
+func (l *LevelSnake) expireFood(now time.Duration) {
+	foods := l.foods[:0]
+	for _, f := range l.foods {
+		if f.kind != FoodBonus || now < f.expires {
+			foods = append(foods, f)
+		}
+	}
+	l.foods = foods
+}
+
+// foodAt returns the index of the food at p, or -1 if there is none
+func (l *LevelSnake) foodAt(p Coord) int {
+	for i, f := range l.foods {
+		if f.pos == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// addFood puts food of the kind on an empty square
+func (l *LevelSnake) addFood(kind FoodKind, now time.Duration) {
+	if pos, ok := l.generateFood(); ok {
+		l.foods = append(l.foods, Food{pos: pos, kind: kind, expires: now + bonusLifetime})
+	}
+}
+
+// checkRoundWon ends the round of a duel once a snake has grown long enough
+func (l *LevelSnake) checkRoundWon() {
+	if !l.config.duel() {
+		return
+	}
+	var short []*Snake
+	for _, s := range l.snakes {
+		if len(s.body) < l.config.lengthForWin {
+			short = append(short, s)
+		}
+	}
+	if len(short) < len(l.snakes) {
+		l.endRound(short)
+	}
+}
+
+// gameIsWon returns true when player one's snake is long enough, or in a duel once either player
+// has won enough rounds
 func (l *LevelSnake) gameIsWon() bool {
-	win := len(l.snake.body) >= lengthForWin
+	if l.config.duel() {
+		return l.winner >= 0
+	}
+	win := len(l.snakes[0].body) >= l.config.lengthForWin
 	if win {
 		PlaySound(winOgg)
 	}
 	return win
 }
 
-func (l *LevelSnake) generateFood(head Coord) Coord {
+func (l *LevelSnake) generateFood() (Coord, bool) {
+	var free []Coord
 	// don't put food on the edges
-	food := Coord{
-		x: rand.Intn(gridWidth-4) + 2,
-		y: rand.Intn(gridHeight-4) + 2,
-	}
-	// don't put food on the snake
-	if food == head {
-		return l.generateFood(head)
-	} else {
-		for i := 0; i < len(l.snake.body); i++ {
-			if l.snake.body[i] == food {
-				return l.generateFood(head)
+	for y := 2; y < l.config.rows()-2; y++ {
+		for x := 2; x < l.config.columns()-2; x++ {
+			p := Coord{x, y}
+			// don't put food on a wall, other food or a snake
+			if l.walls.Get(p) || l.foodAt(p) >= 0 || slices.ContainsFunc(l.snakes, func(s *Snake) bool {
+				return slices.Contains(s.body, p)
+			}) {
+				continue
 			}
+			free = append(free, p)
 		}
 	}
-	return food
+	if len(free) == 0 {
+		return Coord{}, false
+	}
+	return free[rng.Intn(len(free))], true
 }
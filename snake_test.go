@@ -0,0 +1,268 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnakeInterval(t *testing.T) {
+	ms := time.Millisecond
+	config := snakeLevelConfig{startInterval: 500 * ms, minInterval: 200 * ms, intervalStep: 30 * ms}
+	tests := []struct {
+		name   string
+		length int
+		want   time.Duration
+	}{
+		{"Starting length", 1, 500 * ms},
+		{"Grown", 4, 410 * ms},
+		{"At the fastest", 11, 200 * ms},
+		{"Past the fastest", 20, 200 * ms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.interval(tt.length); got != tt.want {
+				t.Errorf("interval(%d) = %v, want %v", tt.length, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSnakeMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []Coord
+		wantErr bool
+	}{
+		{"Walls", "#..\n..#\n", []Coord{{0, 0}, {2, 1}}, false},
+		{"No walls", "...\n...\n", nil, false},
+		{"Windows line endings", "#..\r\n...\r\n", []Coord{{0, 0}}, false},
+		{"Short line", "#..\n..\n", nil, true},
+		{"Too few lines", "...\n", nil, true},
+		{"Unknown square", "..x\n...\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSnakeMap(tt.text, 3, 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSnakeMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var walls []Coord
+			got.ForEach(func(p Coord, wall bool) {
+				if wall {
+					walls = append(walls, p)
+				}
+			})
+			if len(walls) != len(tt.want) {
+				t.Fatalf("parseSnakeMap() walls = %v, want %v", walls, tt.want)
+			}
+			for i := range walls {
+				if walls[i] != tt.want[i] {
+					t.Errorf("parseSnakeMap() walls = %v, want %v", walls, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSnakeMapsLoad(t *testing.T) {
+	for id, config := range snakeLevels {
+		data, err := embeddedAssets.ReadFile(config.mapFile)
+		if err != nil {
+			t.Fatalf("level %v: %v", id, err)
+		}
+		walls, err := parseSnakeMap(string(data), config.columns(), config.rows())
+		if err != nil {
+			t.Fatalf("level %v: %v", id, err)
+		}
+		for _, start := range config.starts {
+			if walls.Get(start.pos) {
+				t.Errorf("level %v: a snake starts on a wall at %v", id, start.pos)
+			}
+		}
+	}
+}
+
+func TestSnakeGoalSurvivesRestarts(t *testing.T) {
+	seedRNG(1)
+	for id, config := range snakeLevels {
+		l := &LevelSnake{}
+		for range 5 {
+			l.Initialize(id)
+			for !l.scoreboard.GameOver() {
+				l.crash()
+			}
+			if l.config.lengthForWin != config.lengthForWin {
+				t.Fatalf("level %v: goal is %d after restarts, want %d", id, l.config.lengthForWin, config.lengthForWin)
+			}
+		}
+	}
+}
+
+func TestSnakeCrashesIntoWall(t *testing.T) {
+	seedRNG(1)
+	l := &LevelSnake{}
+	l.Initialize(LevelIdInsertMode)
+	lives := l.scoreboard.lives
+	// the snake heads east across the middle of the map into the border
+	for range l.config.columns() {
+		if !l.moveSnakes(l.snakes, 0) {
+			break
+		}
+	}
+	if l.scoreboard.lives != lives-1 {
+		t.Errorf("lives = %d, want %d", l.scoreboard.lives, lives-1)
+	}
+	if body := l.snakes[0].body; len(body) != 1 || body[0] != l.config.starts[0].pos {
+		t.Errorf("snake = %v, want a new snake at the start", body)
+	}
+	if l.gameIsWon() {
+		t.Error("gameIsWon() = true for a new snake")
+	}
+}
+
+func TestSnakeDuelRounds(t *testing.T) {
+	seedRNG(1)
+	tests := []struct {
+		name     string
+		one, two Snake
+		wantWins []int
+	}{
+		{"Head on", Snake{body: []Coord{{8, 7}}, direction: east}, Snake{body: []Coord{{10, 7}}, direction: west}, []int{0, 0}},
+		{"Into a body", Snake{body: []Coord{{9, 1}}, direction: south},
+			Snake{body: []Coord{{8, 2}, {9, 2}, {10, 2}}, direction: east}, []int{0, 1}},
+		{"Into a wall", Snake{body: []Coord{{5, 2}}, direction: west},
+			Snake{body: []Coord{{1, 1}}, direction: north}, []int{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LevelSnake{}
+			l.Initialize(LevelIdSnakeDuel)
+			l.foods = nil
+			l.snakes = []*Snake{&tt.one, &tt.two}
+			if l.moveSnakes(l.snakes, 0) {
+				t.Fatal("moveSnakes() = true, want a crash")
+			}
+			if !slices.Equal(l.wins, tt.wantWins) {
+				t.Errorf("wins = %v, want %v", l.wins, tt.wantWins)
+			}
+		})
+	}
+}
+
+func TestSnakeMovesOnTime(t *testing.T) {
+	seedRNG(1)
+	l := &LevelSnake{}
+	l.Initialize(LevelIdSnake)
+	var clock FakeClock
+	start := l.snakes[0].body[0]
+	interval := l.config.interval(1)
+	moves := func() int {
+		return abs(l.snakes[0].body[0].x - start.x)
+	}
+	// the first move is straight away, the next after the interval, however it is stepped
+	clock.Advance(tick)
+	l.Update(&clock)
+	for clock.Now() < interval {
+		clock.Advance(tick)
+		l.Update(&clock)
+	}
+	if moves() != 1 {
+		t.Fatalf("moved %d squares before the interval was up, want 1", moves())
+	}
+	clock.Advance(tick)
+	l.Update(&clock)
+	if moves() != 2 {
+		t.Errorf("moved %d squares once the interval was up, want 2", moves())
+	}
+	clock.Advance(0)
+	l.Update(&clock)
+	if moves() != 2 {
+		t.Errorf("moved %d squares while the clock was paused, want 2", moves())
+	}
+}
+
+func TestSnakeDuelWinner(t *testing.T) {
+	l := &LevelSnake{}
+	l.Initialize(LevelIdSnakeDuel)
+	for round := 1; round <= roundsToWin; round++ {
+		if l.Winner() != "" {
+			t.Fatalf("%s won the duel after %d rounds, want %d", l.Winner(), round-1, roundsToWin)
+		}
+		// player one crashes, player two wins the round
+		l.endRound(l.snakes[:1])
+	}
+	if l.Winner() != playerNames[1] || !l.gameIsWon() {
+		t.Errorf("Winner() = %q, gameIsWon() = %v, want %q and true", l.Winner(), l.gameIsWon(), playerNames[1])
+	}
+	if _, failed := l.Failed(); failed {
+		t.Error("Failed() = true for a duel won by player two")
+	}
+}
+
+func TestSnakeDuelRoundWonByLength(t *testing.T) {
+	l := &LevelSnake{}
+	l.Initialize(LevelIdSnakeDuel)
+	long := l.snakes[1]
+	for len(long.body) < l.config.lengthForWin {
+		long.body = append(long.body, long.body[0])
+	}
+	if l.gameIsWon() || l.wins[1] != 0 {
+		t.Fatalf("gameIsWon() changed the round, wins = %v", l.wins)
+	}
+	l.checkRoundWon()
+	if l.wins[0] != 0 || l.wins[1] != 1 {
+		t.Errorf("wins = %v after player two grew long enough, want [0 1]", l.wins)
+	}
+	if len(l.snakes[1].body) != 1 {
+		t.Errorf("player two is %d squares long after the round, want a new snake", len(l.snakes[1].body))
+	}
+}
+
+func TestSnakeWrapsAroundEdges(t *testing.T) {
+	seedRNG(1)
+	l := &LevelSnake{}
+	l.Initialize(LevelIdSnakeWrap)
+	l.foods = nil
+	lives := l.scoreboard.lives
+	// the snake heads east along an open row, off the right edge and back on the left
+	for range l.config.columns() {
+		if !l.moveSnakes(l.snakes, 0) {
+			t.Fatalf("moveSnakes() = false at %v", l.snakes[0].body[0])
+		}
+	}
+	if l.scoreboard.lives != lives {
+		t.Errorf("lives = %d, want %d", l.scoreboard.lives, lives)
+	}
+	if head := l.snakes[0].body[0]; head != l.config.starts[0].pos {
+		t.Errorf("head = %v, want %v after going all the way round", head, l.config.starts[0].pos)
+	}
+}
+
+func TestGenerateFood(t *testing.T) {
+	seedRNG(1)
+	l := &LevelSnake{}
+	l.Initialize(LevelIdSnake)
+	l.foods = nil
+	l.snakes[0].body = nil
+	// fill every square food can go on but one
+	last := Coord{3, 2}
+	for y := 2; y < l.config.rows()-2; y++ {
+		for x := 2; x < l.config.columns()-2; x++ {
+			if p := (Coord{x, y}); p != last && !l.walls.Get(p) {
+				l.snakes[0].body = append(l.snakes[0].body, p)
+			}
+		}
+	}
+	if got, ok := l.generateFood(); !ok || got != last {
+		t.Errorf("generateFood() = %v, %v, want %v, true", got, ok, last)
+	}
+	l.snakes[0].body = append(l.snakes[0].body, last)
+	if got, ok := l.generateFood(); ok {
+		t.Errorf("generateFood() = %v, true on a full board, want false", got)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+/*
+ * Moves on a gems board and a solver that finds them. A GemMove is one command the player can
+ * type, such as dd or a visual delete. The solver lists the moves a level allows that make a
+ * triple and ranks them by the points for the matches they make, which powers the hint key and
+ * lets new boards be checked for at least one move. There are thousands of visual selections on a
+ * board, so those that can't line up three gems are left out before they are tried on a copy.
+ */
+
+// GemMoveKind is the command a move is made with
+type GemMoveKind int
+
+const (
+	MoveDeleteRows      GemMoveKind = iota // dd or d{n}
+	MoveDeleteSelection                    // v, a selection, then d
+	MoveDeleteGem                          // x
+	MoveReplaceGem                         // r{n}
+	MoveJoinRows                           // J
+	MoveShiftRows                          // >> and <<
+)
+
+const (
+	maxFillAttempts = 100 // random boards tried for one with a move before one is planted
+	maxMoveCount    = 9   // the largest count tried for a command, as d{n} only takes a single digit
+)
+
+// GemMove is a command that changes the board.
+type GemMove struct {
+	kind     GemMoveKind
+	from     Coord // the cursor
+	to       Coord // the other end of a visual selection
+	count    int   // rows deleted, joined or shifted
+	gem      int   // the new gem for a replace
+	shift    int   // columns a row is shifted, negative to the left
+	pulled   int   // gems pulled up by a join
+	overflow joinOverflow
+}
+
+// Apply makes the move. Empty squares left by a delete are not filled. It returns the gems
+// blasted by special gems the move deletes.
+func (m GemMove) Apply(b *Board) []BoardEvent {
+	switch m.kind {
+	case MoveDeleteRows:
+		return b.Delete(b.RowRange(m.from.y, m.count))
+	case MoveDeleteSelection:
+		return b.Delete(BoardRange{m.from, m.to})
+	case MoveDeleteGem:
+		return b.Delete(BoardRange{m.from, m.from})
+	case MoveReplaceGem:
+		b.SetGem(m.from, m.gem)
+	case MoveJoinRows:
+		for range m.count {
+			b.JoinRows(m.from.y, m.pulled, m.overflow)
+		}
+	case MoveShiftRows:
+		for y := m.from.y; y < min(m.from.y+m.count, b.Rows()); y++ {
+			b.ShiftRow(y, m.shift)
+		}
+	}
+	return nil
+}
+
+// Cells returns the squares the move changes, for highlighting a hint.
+func (m GemMove) Cells(b *Board) []Coord {
+	switch m.kind {
+	case MoveDeleteRows, MoveShiftRows:
+		return b.RowRange(m.from.y, m.count).Cells(b.Columns())
+	case MoveJoinRows:
+		return b.RowRange(m.from.y, m.count+1).Cells(b.Columns())
+	case MoveDeleteSelection:
+		return BoardRange{m.from, m.to}.Cells(b.Columns())
+	default:
+		return []Coord{m.from}
+	}
+}
+
+// Keys returns what is typed to make the move once the cursor is on the first square of its cells.
+func (m GemMove) Keys() string {
+	count := ""
+	if m.count > 1 {
+		count = fmt.Sprint(m.count)
+	}
+	switch m.kind {
+	case MoveDeleteRows:
+		if m.count > 1 {
+			return "d" + count + " Enter"
+		}
+		return "dd"
+	case MoveDeleteSelection:
+		return "v, select, d"
+	case MoveDeleteGem:
+		return "x"
+	case MoveReplaceGem:
+		return fmt.Sprint("r", m.gem+1)
+	case MoveJoinRows:
+		if m.count > 1 {
+			count = fmt.Sprint(m.count + 1)
+		}
+		return count + "J"
+	case MoveShiftRows:
+		if m.shift < 0 {
+			return count + "<<"
+		}
+		return count + ">>"
+	}
+	return ""
+}
+
+// IsValid returns true if the move results in a triple or sets off a special gem.
+func (m GemMove) IsValid(b *Board) bool {
+	matches, blasted := m.outcome(b)
+	return len(matches) > 0 || blasted > 0
+}
+
+// mayBeValid returns false for a move that is sure not to be valid, found without trying it on a
+// copy of the board. It only rules out visual selections, and only on a board with no matches.
+func (m GemMove) mayBeValid(b *Board, settled bool) bool {
+	if m.kind != MoveDeleteSelection || !settled {
+		return true
+	}
+	return selectionMayMatch(b, BoardRange{m.from, m.to})
+}
+
+// selectionMayMatch returns true if deleting the selection could line up three gems on a board
+// with no matches. The gems below the selection move up, a column at a time, so a new run down a
+// column crosses the top of the gap, and a new run along a row joins columns that moved up by
+// different amounts. A selection holding a special gem blasts other squares, so it may match.
+func selectionMayMatch(b *Board, r BoardRange) bool {
+	columns, rows := b.Columns(), b.Rows()
+	top := make([]int, columns)     // the first row deleted in each column
+	deleted := make([]int, columns) // the gems deleted in each column
+	for x := range top {
+		top[x] = rows
+	}
+	for _, p := range r.Cells(columns) {
+		if b.Kind(p) != GemNormal {
+			return true
+		}
+		top[p.x] = min(top[p.x], p.y)
+		deleted[p.x]++
+	}
+	// rise returns how far the gem that ends up at x, y moved up
+	rise := func(x, y int) int {
+		if y < top[x] {
+			return 0
+		}
+		return deleted[x]
+	}
+	gemAt := func(x, y int) int {
+		if y+rise(x, y) >= rows {
+			return emptyGem
+		}
+		return b.Gem(Coord{x, y + rise(x, y)})
+	}
+	same := func(a, b, c int) bool {
+		return a != emptyGem && a == b && b == c
+	}
+	for x := range columns {
+		for y := max(top[x]-2, 0); y < top[x] && y+2 < rows; y++ {
+			if same(gemAt(x, y), gemAt(x, y+1), gemAt(x, y+2)) {
+				return true
+			}
+		}
+	}
+	for y := range rows {
+		for x := 0; x+2 < columns; x++ {
+			if rise(x, y) == rise(x+1, y) && rise(x+1, y) == rise(x+2, y) {
+				continue
+			}
+			if same(gemAt(x, y), gemAt(x+1, y), gemAt(x+2, y)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outcome returns the matches the move makes, found on a copy of the board after the gems have
+// moved up to fill the squares it empties, and the number of gems blasted by special gems it deletes.
+func (m GemMove) outcome(b *Board) ([]Match, int) {
+	c := b.Copy()
+	blasted := 0
+	for _, e := range m.Apply(c) {
+		if e.kind == GemBlasted {
+			blasted++
+		}
+	}
+	c.Collapse()
+	return c.Matches(), blasted
+}
+
+// Solver finds the moves on a board made with the commands a level allows.
+type Solver struct {
+	kinds        []GemMoveKind
+	joinGems     int
+	joinOverflow joinOverflow
+}
+
+// RankedMove is a valid move and the points for the matches it makes.
+type RankedMove struct {
+	move  GemMove
+	score int
+}
+
+// Moves returns every move the commands allow, valid or not.
+func (s Solver) Moves(b *Board) []GemMove {
+	var moves []GemMove
+	for _, kind := range s.kinds {
+		switch kind {
+		case MoveDeleteRows:
+			for y := range b.Rows() {
+				for n := 1; n <= min(maxMoveCount, b.Rows()-y); n++ {
+					moves = append(moves, GemMove{kind: kind, from: Coord{0, y}, count: n})
+				}
+			}
+		case MoveDeleteSelection:
+			cells := b.RowRange(0, b.Rows()).Cells(b.Columns())
+			for i, from := range cells {
+				for _, to := range cells[i:] {
+					moves = append(moves, GemMove{kind: kind, from: from, to: to})
+				}
+			}
+		case MoveDeleteGem:
+			for _, p := range b.RowRange(0, b.Rows()).Cells(b.Columns()) {
+				moves = append(moves, GemMove{kind: kind, from: p})
+			}
+		case MoveReplaceGem:
+			for _, p := range b.RowRange(0, b.Rows()).Cells(b.Columns()) {
+				for gem := range b.numGems {
+					if gem != b.Gem(p) {
+						moves = append(moves, GemMove{kind: kind, from: p, gem: gem})
+					}
+				}
+			}
+		case MoveJoinRows:
+			for y := range b.Rows() - 1 {
+				for n := 1; n <= min(maxMoveCount, b.Rows()-1-y); n++ {
+					moves = append(moves, GemMove{kind: kind, from: Coord{0, y}, count: n,
+						pulled: s.joinGems, overflow: s.joinOverflow})
+				}
+			}
+		case MoveShiftRows:
+			for y := range b.Rows() {
+				for n := 1; n <= min(maxMoveCount, b.Rows()-y); n++ {
+					moves = append(moves, GemMove{kind: kind, from: Coord{0, y}, count: n, shift: 1})
+					moves = append(moves, GemMove{kind: kind, from: Coord{0, y}, count: n, shift: -1})
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// Solve returns the valid moves, best first. Moves scoring the same are kept in the order of Moves.
+func (s Solver) Solve(b *Board) []RankedMove {
+	var ranked []RankedMove
+	settled := len(b.Matches()) == 0
+	for _, m := range s.Moves(b) {
+		if !m.mayBeValid(b, settled) {
+			continue
+		}
+		matches, blasted := m.outcome(b)
+		if len(matches) == 0 && blasted == 0 {
+			continue
+		}
+		r := RankedMove{move: m, score: gemScore * blasted}
+		for _, match := range matches {
+			r.score += match.Score()
+		}
+		ranked = append(ranked, r)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	return ranked
+}
+
+// BestMove returns the valid move that scores the most.
+func (s Solver) BestMove(b *Board) (GemMove, bool) {
+	ranked := s.Solve(b)
+	if len(ranked) == 0 {
+		return GemMove{}, false
+	}
+	return ranked[0].move, true
+}
+
+// HasMove returns true if there is at least one valid move, stopping at the first found.
+func (s Solver) HasMove(b *Board) bool {
+	settled := len(b.Matches()) == 0
+	for _, m := range s.Moves(b) {
+		if m.mayBeValid(b, settled) && m.IsValid(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// FillSolvable fills the board with random gems that make no matches, and that the solver finds
+// at least one move on. Gems in matches are chosen again until there are none, and a board with
+// no moves is thrown away and filled again. After maxFillAttempts boards a move is planted.
+func (b *Board) FillSolvable(s Solver) {
+	for range maxFillAttempts {
+		b.FillRandom()
+		for found := true; found; {
+			var mask Grid[bool]
+			found, mask = b.FindMatches()
+			mask.ForEach(func(p Coord, matched bool) {
+				if matched {
+					b.SetGem(p, b.rng.Intn(b.numGems))
+				}
+			})
+		}
+		if s.HasMove(b) {
+			return
+		}
+	}
+	if !b.plantMove() {
+		log.Println("No move could be planted on the board")
+	}
+}
+
+// plantMove lines up gems in the first column so that deleting the gem in row 2, with dd, x or a
+// visual selection, or replacing it, makes three in a row. Returns false if every gem that
+// could be planted would make a match.
+func (b *Board) plantMove() bool {
+	gap := Coord{0, 2}
+	for gem := range b.numGems {
+		if b.Gem(gap) == gem {
+			continue
+		}
+		c := b.Copy()
+		for _, y := range []int{0, 1, 3} {
+			c.SetGem(Coord{0, y}, gem)
+		}
+		if len(c.Matches()) == 0 {
+			b.gems, b.kinds = c.gems, c.kinds
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGeneratedBoardsAreSolvable(t *testing.T) {
+	for id, config := range gemsLevels {
+		solver := Solver{kinds: config.moves, joinGems: config.columns / 2, joinOverflow: config.joinOverflow}
+		for seed := range int64(10) {
+			b := NewBoard(config.columns, numGemRows, config.numGems, rand.New(rand.NewSource(seed)))
+			b.FillSolvable(solver)
+			if found, _ := b.FindMatches(); found {
+				t.Errorf("level %d seed %d: board starts with a match", id, seed)
+			}
+			move, ok := solver.BestMove(b)
+			if !ok {
+				t.Errorf("level %d seed %d: board has no moves", id, seed)
+				continue
+			}
+			if !move.IsValid(b) {
+				t.Errorf("level %d seed %d: best move %+v does not make a triple", id, seed, move)
+			}
+		}
+	}
+}
+
+func TestSolverRanksMoves(t *testing.T) {
+	// deleting row 1 lines up three 0s, deleting rows 1 and 2 lines up four
+	b := newTestBoard([][]int{
+		{0, 1, 2, 3},
+		{1, 2, 3, 4},
+		{4, 3, 4, 1},
+		{0, 1, 2, 3},
+		{0, 2, 3, 4},
+		{0, 3, 4, 2},
+	})
+	solver := Solver{kinds: []GemMoveKind{MoveDeleteRows}}
+	ranked := solver.Solve(b)
+	if len(ranked) == 0 {
+		t.Fatalf("Solve() found no moves")
+	}
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].score > ranked[i-1].score {
+			t.Errorf("move %d scores %d, more than the move before it", i, ranked[i].score)
+		}
+	}
+	best := ranked[0].move
+	if best.from.y != 1 || best.count != 2 {
+		t.Errorf("best move deletes %d rows from row %d; want 2 from row 1", best.count, best.from.y)
+	}
+	if best.Keys() != "d2 Enter" {
+		t.Errorf("Keys() = %q; want %q", best.Keys(), "d2 Enter")
+	}
+}
+
+func TestGemMoveKeys(t *testing.T) {
+	tests := []struct {
+		move GemMove
+		want string
+	}{
+		{GemMove{kind: MoveDeleteRows, count: 1}, "dd"},
+		{GemMove{kind: MoveDeleteRows, count: 3}, "d3 Enter"},
+		{GemMove{kind: MoveDeleteGem}, "x"},
+		{GemMove{kind: MoveReplaceGem, gem: 2}, "r3"},
+		{GemMove{kind: MoveJoinRows, count: 1}, "J"},
+		{GemMove{kind: MoveJoinRows, count: 2}, "3J"},
+		{GemMove{kind: MoveShiftRows, count: 1, shift: 1}, ">>"},
+		{GemMove{kind: MoveShiftRows, count: 2, shift: -1}, "2<<"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.move.Keys(); got != tt.want {
+			t.Errorf("Keys() of %+v = %q; want %q", tt.move, got, tt.want)
+		}
+	}
+}
+
+func TestSelectionPruning(t *testing.T) {
+	config := gemsLevels[LevelIdGemsVM]
+	solver := Solver{kinds: config.moves}
+	for seed := range int64(5) {
+		// a new board has no special gems, where the check is exact
+		b := NewBoard(config.columns, numGemRows, config.numGems, rand.New(rand.NewSource(seed)))
+		b.FillSolvable(solver)
+		for _, m := range solver.Moves(b) {
+			if got, want := m.mayBeValid(b, true), m.IsValid(b); got != want {
+				t.Errorf("seed %d: selection %v to %v mayBeValid() = %v, IsValid() = %v", seed, m.from, m.to, got, want)
+			}
+		}
+	}
+}
+
+func TestFillSolvablePlantsMove(t *testing.T) {
+	// a solver with no commands never finds a move, so one is planted
+	b := NewBoard(6, numGemRows, 4, rand.New(rand.NewSource(1)))
+	b.FillSolvable(Solver{})
+	if found, _ := b.FindMatches(); found {
+		t.Error("board with a planted move has a match")
+	}
+	for _, kind := range []GemMoveKind{MoveDeleteRows, MoveDeleteSelection, MoveDeleteGem} {
+		if !(Solver{kinds: []GemMoveKind{kind}}).HasMove(b) {
+			t.Errorf("no move of kind %d on a board with a planted move", kind)
+		}
+	}
+}
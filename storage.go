@@ -0,0 +1,49 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+/*
+ * Saved data on the desktop is kept in a file for each key in a viple directory under the
+ * user's config directory, ~/.config/viple on Linux.
+ */
+
+// savePath returns the file the data saved under key is kept in
+func savePath(key string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "viple", key+".json"), nil
+}
+
+// loadSaved returns the data saved under key, nil if nothing has been saved
+func loadSaved(key string) ([]byte, error) {
+	path, err := savePath(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// save saves data under key, replacing what was saved before
+func save(key string, data []byte) error {
+	path, err := savePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,53 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+/*
+ * Saved data in the browser is kept in the page's localStorage, an item for each key.
+ */
+
+const storagePrefix = "viple." // put before each key so the items don't clash with the page's
+
+// localStorage returns the browser's storage, which is missing outside a page
+func localStorage() (js.Value, error) {
+	storage := js.Global().Get("localStorage")
+	if storage.IsUndefined() || storage.IsNull() {
+		return js.Value{}, errors.New("localStorage is not available")
+	}
+	return storage, nil
+}
+
+// loadSaved returns the data saved under key, nil if nothing has been saved
+func loadSaved(key string) ([]byte, error) {
+	storage, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+	item := storage.Call("getItem", storagePrefix+key)
+	if item.IsNull() {
+		return nil, nil
+	}
+	return []byte(item.String()), nil
+}
+
+// save saves data under key, replacing what was saved before. The browser throws when the
+// storage is full or blocked, which is returned as an error.
+func save(key string, data []byte) (err error) {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("localStorage.setItem: %v", r)
+		}
+	}()
+	storage.Call("setItem", storagePrefix+key, string(data))
+	return nil
+}
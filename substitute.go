@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+/*
+ * LevelSubstitute provides practice of the ex substitute command. Each puzzle shows a buffer
+ * of words and the target it must be turned into. The player edits the buffer with :s,
+ * using ranges and the g and c flags.
+ */
+
+const (
+	substituteLeft   = 60
+	substituteTop    = 80
+	substituteTarget = 440
+)
+
+var (
+	colorCursorLine  = mediumCoal
+	colorDoneLine    = mediumGreen
+	colorMatchHilite = darkButter
+)
+
+// subPuzzle is a buffer and the text it must be changed into.
+type subPuzzle struct {
+	lines  []string
+	target []string
+	cursor int
+}
+
+var substitutePuzzles = []subPuzzle{
+	{
+		// :s/cat/dog/
+		lines:  []string{"the cat sat on the mat", "the cat ate the rat"},
+		target: []string{"the dog sat on the mat", "the cat ate the rat"},
+	},
+	{
+		// :s/red/gold/g
+		lines:  []string{"red gem, red gem, blue gem", "red gem, blue gem, red gem"},
+		target: []string{"gold gem, gold gem, blue gem", "red gem, blue gem, red gem"},
+	},
+	{
+		// :%s/ruby/pearl/g
+		lines: []string{
+			"ruby emerald ruby",
+			"sapphire ruby",
+			"emerald emerald",
+			"ruby ruby ruby",
+			"opal",
+		},
+		target: []string{
+			"pearl emerald pearl",
+			"sapphire pearl",
+			"emerald emerald",
+			"pearl pearl pearl",
+			"opal",
+		},
+	},
+	{
+		// :5,10s/rock/gold/g
+		lines: []string{
+			"rock rock", "rock rock", "rock rock", "rock rock",
+			"rock rock", "rock rock", "rock rock", "rock rock", "rock rock", "rock rock",
+			"rock rock", "rock rock",
+		},
+		target: []string{
+			"rock rock", "rock rock", "rock rock", "rock rock",
+			"gold gold", "gold gold", "gold gold", "gold gold", "gold gold", "gold gold",
+			"rock rock", "rock rock",
+		},
+	},
+	{
+		// :%s/one/two/gc answering y, n, y, then n, y, then n, y, y, n
+		lines: []string{
+			"one one one",
+			"one one",
+			"one one one one",
+		},
+		target: []string{
+			"two one two",
+			"one two",
+			"one two two one",
+		},
+	},
+}
+
+type LevelSubstitute struct {
+	cmdLine     string // text typed on the command line after ':'
+	confirm     *substituteConfirm
+	cursorLine  int
+	history     [][]string // buffers saved before each change, for undo
+	level       LevelID
+	lines       []string
+	marks       map[rune]int
+	message     string
+	pendingMark bool
+	puzzle      int
+	viMode      VIMode
+}
+
+func (l *LevelSubstitute) Draw(screen *ebiten.Image, now time.Duration) {
+	screen.Fill(darkCoal)
+	p := substitutePuzzles[l.puzzle]
+
+	drawBufferString(screen, fmt.Sprintf("Puzzle %d of %d", l.puzzle+1, len(substitutePuzzles)),
+		substituteLeft, 20, 0, 0, bufferDimText)
+	drawBufferString(screen, "Target", substituteTarget, substituteTop, 0, -1, bufferDimText)
+
+	// highlight the cursor line and any match awaiting confirmation
+	drawBufferSpan(screen, substituteLeft, substituteTop, 0, 30, l.cursorLine, colorCursorLine)
+	if l.confirm != nil {
+		if line, start, end, ok := l.confirm.Current(l.lines); ok {
+			drawBufferSpan(screen, substituteLeft, substituteTop, start, max(end, start+1), line, colorMatchHilite)
+		}
+	}
+
+	// lines that already match the target are drawn in green
+	drawBufferLines(screen, l.lines, substituteLeft, substituteTop, true, bufferText)
+	for y, line := range l.lines {
+		if y < len(p.target) && line == p.target[y] {
+			drawBufferString(screen, line, substituteLeft, substituteTop, 0, y, colorDoneLine)
+		}
+	}
+	drawBufferLines(screen, p.target, substituteTarget, substituteTop, false, bufferDimText)
+
+	// show marks in the gutter
+	for mark, y := range l.marks {
+		drawBufferString(screen, string(mark), substituteLeft-5*bufferCharWidth, substituteTop, 0, y, darkButter)
+	}
+
+	// the command line is at the bottom of the screen
+	bottom := screenHeight - bufferLineHeight - 10
+	switch {
+	case l.viMode == CommandLineMode:
+		drawBufferString(screen, ":"+l.cmdLine, substituteLeft, bottom, 0, 0, bufferText)
+		if blinkPhase(now, blinkInterval) == 0 {
+			drawBufferCell(screen, substituteLeft, bottom, len(l.cmdLine)+1, 0, whiteCursor)
+		}
+	case l.confirm != nil:
+		prompt := fmt.Sprintf("replace with %s (y/n/a/q/l)?", l.confirm.sub.replacement)
+		drawBufferString(screen, prompt, substituteLeft, bottom, 0, 0, darkButter)
+	default:
+		drawBufferString(screen, l.message, substituteLeft, bottom, 0, 0, bufferText)
+	}
+}
+
+func (l *LevelSubstitute) Initialize(id LevelID) {
+	l.level = id
+	l.puzzle = 0
+	l.loadPuzzle()
+}
+
+func (l *LevelSubstitute) Update(clock Clock) (bool, error) {
+	// 'c' is a substitute flag so only allow the cheat key in normal mode
+	if l.viMode == NormalMode && l.confirm == nil && isCheatKeyPressed() {
+		return true, nil
+	}
+
+	switch {
+	case l.confirm != nil:
+		l.updateConfirm()
+	case l.viMode == CommandLineMode:
+		l.updateCommandLine()
+	default:
+		l.updateNormalMode()
+	}
+
+	if equals(l.lines, substitutePuzzles[l.puzzle].target) {
+		if l.puzzle == len(substitutePuzzles)-1 {
+			return true, nil
+		}
+		PlaySound(tripleOgg)
+		l.puzzle++
+		l.loadPuzzle()
+	}
+	return false, nil
+}
+
+// execute runs the command typed on the command line
+func (l *LevelSubstitute) execute() {
+	ctx := exContext{
+		cursor:   l.cursorLine,
+		lastLine: len(l.lines) - 1,
+		marks:    l.marks,
+	}
+	sub, err := parseSubstitute(l.cmdLine, ctx)
+	if err != nil {
+		l.fail(err.Error())
+		return
+	}
+
+	l.saveUndo()
+	if sub.confirm {
+		l.confirm = newSubstituteConfirm(sub, l.lines)
+		if l.confirm.Done() {
+			l.confirm = nil
+			l.undo()
+			l.fail(errPatternNotFound.Error())
+		}
+		return
+	}
+	n := sub.Apply(l.lines)
+	if n == 0 {
+		l.undo()
+		l.fail(errPatternNotFound.Error())
+		return
+	}
+	l.message = substitutionMessage(n)
+	l.cursorLine = sub.lines.end
+}
+
+func (l *LevelSubstitute) fail(message string) {
+	l.message = message
+	PlaySound(failOgg)
+}
+
+func (l *LevelSubstitute) loadPuzzle() {
+	p := substitutePuzzles[l.puzzle]
+	l.lines = make([]string, len(p.lines))
+	copy(l.lines, p.lines)
+	l.cursorLine = p.cursor
+	l.history = nil
+	l.marks = make(map[rune]int)
+	l.message = ""
+	l.cmdLine = ""
+	l.confirm = nil
+	l.pendingMark = false
+	l.viMode = NormalMode
+}
+
+func (l *LevelSubstitute) saveUndo() {
+	saved := make([]string, len(l.lines))
+	copy(saved, l.lines)
+	l.history = append(l.history, saved)
+}
+
+func (l *LevelSubstitute) undo() bool {
+	if len(l.history) == 0 {
+		return false
+	}
+	l.lines = l.history[len(l.history)-1]
+	l.history = l.history[:len(l.history)-1]
+	l.cursorLine = min(l.cursorLine, len(l.lines)-1)
+	return true
+}
+
+func (l *LevelSubstitute) updateCommandLine() {
+	for _, ch := range globalChars {
+		l.cmdLine += string(ch)
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		l.viMode = NormalMode
+		l.cmdLine = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		if l.cmdLine == "" {
+			// backspace over the ':' leaves command-line mode
+			l.viMode = NormalMode
+		} else {
+			l.cmdLine = l.cmdLine[:len(l.cmdLine)-1]
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		l.viMode = NormalMode
+		l.execute()
+		l.cmdLine = ""
+	}
+	clearKeystrokes()
+}
+
+func (l *LevelSubstitute) updateConfirm() {
+	answers := globalChars
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		answers = []rune{'q'}
+	}
+	for _, ch := range answers {
+		if !strings.ContainsRune("ynaql", ch) {
+			continue
+		}
+		l.confirm.Answer(ch, l.lines)
+		if l.confirm.Done() {
+			l.message = substitutionMessage(l.confirm.made)
+			if l.confirm.made == 0 {
+				// nothing changed so there is nothing to undo
+				l.history = l.history[:len(l.history)-1]
+			}
+			l.cursorLine = l.confirm.sub.lines.end
+			l.confirm = nil
+			break
+		}
+	}
+	clearKeystrokes()
+}
+
+func (l *LevelSubstitute) updateNormalMode() {
+	for i, ch := range globalChars {
+		if l.pendingMark {
+			l.pendingMark = false
+			if ch >= 'a' && ch <= 'z' {
+				l.marks[ch] = l.cursorLine
+			} else {
+				PlaySound(failOgg)
+			}
+			continue
+		}
+		switch ch {
+		case ':':
+			// the rest of what was typed goes on the command line
+			l.viMode = CommandLineMode
+			l.cmdLine = string(globalChars[i+1:])
+			l.message = ""
+			clearKeystrokes()
+			return
+		case 'j':
+			l.cursorLine = min(l.cursorLine+1, len(l.lines)-1)
+		case 'k':
+			l.cursorLine = max(l.cursorLine-1, 0)
+		case 'm':
+			l.pendingMark = true
+		case 'u':
+			if l.undo() {
+				l.message = "1 change undone"
+			} else {
+				l.fail("Already at oldest change")
+			}
+		}
+	}
+	clearKeystrokes()
+}
+
+// substitutionMessage is the message vi shows after a substitute command
+func substitutionMessage(n int) string {
+	if n == 1 {
+		return "1 substitution"
+	}
+	return fmt.Sprintf("%d substitutions", n)
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestColonOpensCommandLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		typed       string
+		wantCmdLine string
+		wantCursor  int
+	}{
+		{"Colon alone", ":", "", 0},
+		{"Typed with the colon", ":s/u/j/", "s/u/j/", 0},
+		{"Moved before the colon", "j:k", "k", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LevelSubstitute{}
+			l.Initialize(LevelIdSubstitute)
+			l.cursorLine = 0
+			globalChars = []rune(tt.typed)
+			defer func() { globalChars = nil }()
+			l.updateNormalMode()
+			if l.viMode != CommandLineMode || l.cmdLine != tt.wantCmdLine || l.cursorLine != tt.wantCursor {
+				t.Errorf("mode %v, command line %q, cursor %d, want command-line mode, %q, %d",
+					l.viMode, l.cmdLine, l.cursorLine, tt.wantCmdLine, tt.wantCursor)
+			}
+		})
+	}
+}
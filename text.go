@@ -9,10 +9,14 @@ func IntroText(level LevelID) string {
 	case LevelIdFlappy:
 		return `Learn vi by playing classic games. Your first challenge 
 is to navigate the pufferfish through the obstacles. 
-Pass seven obstacles without fail to advance to the next level.
-
-J -- Up
-K -- Down
+Pass eight obstacles to advance to the next level.
+Hitting one costs a life, you have three.
+Swim onto a bubble for points, the number by it is
+the count that takes you there.
+
+J -- Down
+K -- Up
+3, J -- Down three lanes, 3, K goes up
 Q -- Quit
 
 
@@ -23,22 +27,39 @@ Music and SFX by Gianni Bellucci, all rights reserved`
 		return `Clear the bricks to advance to the next level
 
 H to move left
-K to move right`
+L to move right
+
+Darker bricks take more hits to break.
+Grey bricks can't be broken, you don't need to clear them.
+
+Yellow bricks drop power-ups. Catch them, then type:
+W -- Widen the paddle
+3, L -- Dash three paddle widths right, 3, H dashes left
+Y -- Yank a copy of each ball
+U -- Undo losing a ball`
 	case LevelIdSnake:
 		return `Guide the snake using the H, J, K, L keys.
-Eat the apples to grow the snake longer.`
+Eat the apples to grow the snake longer.
+Yellow bonus food is worth more, but does not last.
+Type a count to dash, 3, L moves three squares right.
+Crashing costs a life, you have three.`
 
 	case LevelIdInsertMode:
 		return `Enter Insert Mode to eat the apple.
 Exit Insert Mode to move the snake.
+Avoid the walls and the purple poison,
+it shrinks the snake.
 
 I - enter insert mode
 Esc - exit insert mode`
 
 	case LevelIdBricksHJKL:
 		return `Move the horizontal paddles left and right (H, L)
-and the veritial paddle up and down (J, K) to defend 
-all four edges
+and the vertical paddles up and down (J, K) to defend
+all four edges.
+
+The ball gets faster the longer you keep it in play.
+You have three lives, lose them all and the bricks come back.
 
 Clear all bricks to advance to the next level.`
 
@@ -52,7 +73,11 @@ Matching gems will turn the squares gold.
 Turn all squares gold to advance to the next level. 
 
 Be careful. If you you try to delete a line that doesn't 
-match up three jewels you'll lose gold!`
+match up three jewels you'll lose gold!
+
+Line up 4 or more to make a special jewel. Delete it or
+match it to clear the jewels around it.
+Stuck? Press F1 for a hint.`
 	case LevelIdGemsVM:
 		return `Visual Mode in VI lets you make a text selection.
 
@@ -61,9 +86,102 @@ to select jewels. Press D to delete the selection.
 Escape to exit visual mode.
 
 Make sure deleting connects three identical jewels!`
+	case LevelIdGemsReplace:
+		return `Change single jewels to connect 3 matching jewels.
+Each jewel has a number, shown on the left.
+
+R, [1, 2, 3, ...] -- Replace the jewel under the cursor
+X -- Delete the jewel under the cursor
+~ -- Change the jewel to the next one and move right
+Shift-R -- Replace mode, type jewel numbers to overwrite
+           jewels one after another. Escape to finish.
+
+Every change must connect three identical jewels!
+You have 60 moves. The fewer you use, the more stars you earn.`
+	case LevelIdGemsJoin:
+		return `Move whole rows of jewels to connect 3 matching jewels.
+
+Shift-J -- Join, pull three jewels up from the row below
+           onto the end of the row. The jewels pushed off
+           the start of the row and the rest of the row
+           below are lost.
+>, > -- Shift the row right, the last jewel wraps around
+<, < -- Shift the row left, the first jewel wraps around
+
+Type a number first to change several rows, 3, >, >
+shifts three rows. Every change must connect three
+identical jewels! Beat the 4 minute clock, the faster
+you are, the more stars you earn.`
+	case LevelIdSubstitute:
+		return `The substitute command replaces text in a range of lines.
+Change the buffer on the left to match the target on the right.
+
+:s/old/new/ -- Replace the first old on the cursor line
+:s/old/new/g -- Replace every old on the cursor line
+:%s/old/new/g -- Replace every old in the buffer
+:5,10s/old/new/g -- Replace in lines 5 to 10
+:s/old/new/gc -- Confirm each replacement with y, n, a, q or l
+
+Ranges can use . for the cursor line, $ for the last line and
+'a for a line marked with ma. j, k move the cursor, u undoes.`
+	case LevelIdMarks:
+		return `Marks remember places so you can jump back to them.
+Mark each gem, then return to the gems as fast as you can.
+
+M, [a-z] -- Set a mark
+', [a-z] -- Jump to the line of a mark
+` + "`" + `, [a-z] -- Jump to the exact position of a mark
+', ' -- Jump back to where you were before the last jump
+Ctrl-O, Ctrl-I -- Move back and forward through the jump list
+
+Counts work with H, J, K, L, so 10J moves down ten lines.`
+	case LevelIdBrackets:
+		return `% jumps between matching brackets: (), [] and {}.
+Change the buffer to match the target below it.
+
+% -- Jump to the matching bracket
+D, % -- Delete from the cursor to the matching bracket
+F, [char] -- Move to the next char on the line
+0, $ -- Move to the start or end of the line
+U -- Undo
+
+If the cursor is not on a bracket, % uses the next bracket on the line.
+Brackets inside quoted strings are skipped.`
+	case LevelIdScroll:
+		return `The tower of jewels is too tall for the screen.
+Scroll to find the gold row, move the cursor onto it
+and place it where you are asked.
+
+Ctrl-D, Ctrl-U -- Scroll down or up half a screen
+Ctrl-F, Ctrl-B -- Scroll forward or back a whole screen
+Z, T -- Put the cursor row at the top of the screen
+Z, Z -- Put the cursor row in the middle of the screen
+Z, B -- Put the cursor row at the bottom of the screen`
 	case LevelIdGemsEnd:
 		return `Congratulations you have completed all the learning levels.
-Use all the skills you've learned toto complete this level!`
+Use all the skills you've learned toto complete this level!
+You have 80 moves and 10 minutes.`
+	case LevelIdSnakeDuel:
+		return `Two snakes share the board and the apples.
+Player one steers with H, J, K, L, player two with the arrow keys.
+Crash into a wall or a snake and you lose the round,
+meet head on and you both lose it.
+Grow to 12 squares to win the round.
+The first to win 3 rounds wins the duel.`
+	case LevelIdFlappyGravity:
+		return `The pufferfish has lost its puff and sinks.
+Tap K to flap it up through the gaps.
+Pass eight obstacles to win.
+Hitting one costs a life, you have three.
+
+K -- Flap up
+Q -- Quit`
+	case LevelIdSnakeWrap:
+		return `The edges are open, leave one edge of the screen
+to come back on the other. The snake is faster
+and there is purple poison, it shrinks the snake.
+Grow to 22 squares to win.
+Crashing into a wall costs a life, you have three.`
 
 	default:
 		log.Println("Unknown Level ", level)
@@ -87,8 +205,26 @@ func TitleText(level LevelID) string {
 		return `Connect Three!`
 	case LevelIdGemsVM:
 		return `Visual Mode`
+	case LevelIdGemsReplace:
+		return `Replace`
+	case LevelIdGemsJoin:
+		return `Join and Shift`
+	case LevelIdSubstitute:
+		return `Substitute!`
+	case LevelIdMarks:
+		return `Marks and Jumps`
+	case LevelIdBrackets:
+		return `Bracket Matching`
+	case LevelIdScroll:
+		return `Scrolling`
 	case LevelIdGemsEnd:
 		return `Challenge Level!`
+	case LevelIdSnakeDuel:
+		return `Snake Duel!`
+	case LevelIdFlappyGravity:
+		return `Sink or Swim!`
+	case LevelIdSnakeWrap:
+		return `Snake Without Edges!`
 	default:
 		log.Println("Unknown Level ", level)
 		return "Unknown Level!"
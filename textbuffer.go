@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+/*
+ * Drawing of monospaced text for the levels that edit a buffer of text.
+ * Text is laid out on a grid of character cells so that a column and line
+ * in the buffer map directly to a position on the screen.
+ */
+
+const (
+	bufferCharWidth  = 12 // advance of a character in the 20 point mono face
+	bufferFontSize   = 20
+	bufferLineHeight = 26
+)
+
+var (
+	bufferFace     font.Face
+	bufferText     = lightAluminium
+	bufferDimText  = mediumAluminium
+	bufferLineNums = darkAluminium
+)
+
+// getBufferFace returns the face used for text buffers, creating it on first use.
+func getBufferFace() font.Face {
+	if bufferFace == nil {
+		bufferFace = newMonoFace(bufferFontSize)
+	}
+	return bufferFace
+}
+
+// bufferCellToScreen converts a column and line of a buffer drawn at left, top into screen coordinates.
+func bufferCellToScreen(left, top, col, line int) Coord {
+	return Coord{left + col*bufferCharWidth, top + line*bufferLineHeight}
+}
+
+// drawBufferCell fills the background of the character cell at col, line.
+func drawBufferCell(screen *ebiten.Image, left, top, col, line int, clr color.Color) {
+	drawBufferSpan(screen, left, top, col, col+1, line, clr)
+}
+
+// drawBufferSpan fills the background of the cells from col start up to, but not including, end.
+func drawBufferSpan(screen *ebiten.Image, left, top, start, end, line int, clr color.Color) {
+	pos := bufferCellToScreen(left, top, start, line)
+	vector.DrawFilledRect(screen, float32(pos.x), float32(pos.y),
+		float32((end-start)*bufferCharWidth), bufferLineHeight, clr, false)
+}
+
+// drawBufferString draws s with its first character in the cell at col, line.
+func drawBufferString(screen *ebiten.Image, s string, left, top, col, line int, clr color.Color) {
+	face := getBufferFace()
+	pos := bufferCellToScreen(left, top, col, line)
+	// text.Draw positions text by its baseline
+	baseline := pos.y + face.Metrics().Ascent.Ceil() + (bufferLineHeight-face.Metrics().Height.Ceil())/2
+	text.Draw(screen, s, face, pos.x, baseline, clr)
+}
+
+// drawBufferLines draws each line of a buffer, with line numbers in the gutter when numbered is set.
+func drawBufferLines(screen *ebiten.Image, lines []string, left, top int, numbered bool, clr color.Color) {
+	for y, line := range lines {
+		if numbered {
+			drawBufferString(screen, lineNumber(y+1), left-4*bufferCharWidth, top, 0, y, bufferLineNums)
+		}
+		drawBufferString(screen, line, left, top, 0, y, clr)
+	}
+}
+
+// lineNumber formats a line number right aligned in a three character gutter.
+func lineNumber(n int) string {
+	return fmt.Sprintf("%3d", n)
+}
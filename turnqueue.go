@@ -0,0 +1,74 @@
+package main
+
+/*
+ * The turns typed in the snake levels. Each h, j, k or l typed is queued and the snake takes one
+ * turn each time it moves, so quick turns such as k then l within a single move are both made.
+ * A count turns the snake and dashes it that many squares straight away, 3l moves it three
+ * squares east.
+ */
+
+const maxQueuedTurns = 3 // turns typed ahead of the snake
+
+var snakeCommands = []commandSpec{
+	{keys: "h"},
+	{keys: "j"},
+	{keys: "k"},
+	{keys: "l"},
+}
+
+// snakeDirections is the direction each command turns the snake
+var snakeDirections = map[string]Direction{
+	"h": west,
+	"j": south,
+	"k": north,
+	"l": east,
+}
+
+// Turn is a change of direction typed by the player
+type Turn struct {
+	direction Direction
+	count     int // squares to dash, 0 to wait for the next move
+}
+
+// TurnQueue is the turns typed but not yet taken by the snake
+type TurnQueue struct {
+	turns []Turn
+}
+
+// opposite returns the direction facing away from d
+func opposite(d Direction) Direction {
+	return (d + 2) % 4
+}
+
+// Push queues a turn, returning false if it would reverse the snake onto itself or the queue is
+// full. heading is the direction of the snake, which the first turn in the queue is made from.
+// A turn that keeps the snake heading the same way is only queued for a dash.
+func (q *TurnQueue) Push(turn Turn, heading Direction) bool {
+	if len(q.turns) > 0 {
+		heading = q.turns[len(q.turns)-1].direction
+	}
+	if turn.direction == opposite(heading) || len(q.turns) >= maxQueuedTurns {
+		return false
+	}
+	if turn.direction != heading || turn.count > 0 {
+		q.turns = append(q.turns, turn)
+	}
+	return true
+}
+
+// Peek returns the next turn without taking it
+func (q *TurnQueue) Peek() (Turn, bool) {
+	if len(q.turns) == 0 {
+		return Turn{}, false
+	}
+	return q.turns[0], true
+}
+
+// Pop takes the next turn
+func (q *TurnQueue) Pop() (Turn, bool) {
+	turn, ok := q.Peek()
+	if ok {
+		q.turns = q.turns[1:]
+	}
+	return turn, ok
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTurnQueuePush(t *testing.T) {
+	tests := []struct {
+		name    string
+		queued  []Turn
+		turn    Turn
+		heading Direction
+		want    []Turn
+		wantOk  bool
+	}{
+		{"Turn", nil, Turn{north, 0}, east, []Turn{{north, 0}}, true},
+		{"Reverse", nil, Turn{west, 0}, east, nil, false},
+		{"Same way is dropped", nil, Turn{east, 0}, east, nil, true},
+		{"Same way with a count dashes", nil, Turn{east, 3}, east, []Turn{{east, 3}}, true},
+		{"Quick turns", []Turn{{north, 0}}, Turn{west, 0}, east, []Turn{{north, 0}, {west, 0}}, true},
+		{"Reverse of the last queued", []Turn{{north, 0}}, Turn{south, 0}, east, []Turn{{north, 0}}, false},
+		{"Back the way it was heading", []Turn{{north, 0}}, Turn{west, 0}, east, []Turn{{north, 0}, {west, 0}}, true},
+		{"Full", []Turn{{north, 0}, {east, 0}, {south, 0}}, Turn{west, 0}, east,
+			[]Turn{{north, 0}, {east, 0}, {south, 0}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := TurnQueue{turns: slices.Clone(tt.queued)}
+			ok := q.Push(tt.turn, tt.heading)
+			if ok != tt.wantOk || !slices.Equal(q.turns, tt.want) {
+				t.Errorf("Push() = %v, %v, want %v, %v", q.turns, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestTurnQueuePop(t *testing.T) {
+	q := TurnQueue{}
+	q.Push(Turn{north, 0}, east)
+	q.Push(Turn{west, 2}, east)
+	for _, want := range []Turn{{north, 0}, {west, 2}} {
+		if got, ok := q.Pop(); !ok || got != want {
+			t.Errorf("Pop() = %v, %v, want %v, true", got, ok, want)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Error("Pop() of an empty queue = true")
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+/*
+ * Tweens animate the position, scale, rotation and alpha of something drawn. A tween moves one
+ * channel of a Transform from a value to another over a duration, shaped by an easing function.
+ * Tweens are put in sequences, played one after another, and parallel groups, played together,
+ * and any of them can call a function as it completes. They are advanced by the step of the
+ * clock in Update and Draw only reads the Transform. A step that runs past the end of a tween in
+ * a sequence goes on into the next, so a sequence takes as long as its tweens added up.
+ */
+
+// Easing maps the progress of a tween, from 0 to 1, onto how far along its values it is
+type Easing func(t float64) float64
+
+func Linear(t float64) float64 {
+	return t
+}
+
+func EaseInQuad(t float64) float64 {
+	return t * t
+}
+
+func EaseOutQuad(t float64) float64 {
+	return t * (2 - t)
+}
+
+func EaseInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+// EaseOutBack overshoots the end a little before settling on it
+func EaseOutBack(t float64) float64 {
+	const overshoot = 1.70158
+	t--
+	return 1 + t*t*((overshoot+1)*t+overshoot)
+}
+
+// TweenChannel is a property of a Transform that a tween animates
+type TweenChannel int
+
+const (
+	channelNone TweenChannel = iota - 1 // changes nothing, for a delay
+	ChannelX
+	ChannelY
+	ChannelScale
+	ChannelRotation
+	ChannelAlpha
+)
+
+// Transform is where and how something is drawn. x and y are the top left corner, scale and
+// rotation, in radians, are about its center.
+type Transform struct {
+	x, y     float64
+	scale    float64
+	rotation float64
+	alpha    float64
+}
+
+// NewTransform returns a transform at x, y drawn at full size and opacity
+func NewTransform(x, y float64) Transform {
+	return Transform{x: x, y: y, scale: 1, alpha: 1}
+}
+
+// set sets a channel of the transform
+func (t *Transform) set(channel TweenChannel, value float64) {
+	switch channel {
+	case ChannelX:
+		t.x = value
+	case ChannelY:
+		t.y = value
+	case ChannelScale:
+		t.scale = value
+	case ChannelRotation:
+		t.rotation = value
+	case ChannelAlpha:
+		t.alpha = value
+	}
+}
+
+// Apply sets the geometry and alpha of op to draw something width by height with the transform,
+// after any scaling already in op.GeoM
+func (t Transform) Apply(op *ebiten.DrawImageOptions, width, height float64) {
+	op.GeoM.Translate(-width/2, -height/2)
+	op.GeoM.Scale(t.scale, t.scale)
+	op.GeoM.Rotate(t.rotation)
+	op.GeoM.Translate(t.x+width/2, t.y+height/2)
+	op.ColorScale.ScaleAlpha(float32(t.alpha))
+}
+
+// Tweener is advanced by each step of the clock, changing a Transform
+type Tweener interface {
+	// Step advances by dt of game time, returning true once it has completed
+	Step(t *Transform, dt time.Duration) bool
+	// Overshoot returns the game time of the last step left over after completing
+	Overshoot() time.Duration
+}
+
+// Tween moves one channel from a value to another
+type Tween struct {
+	channel    TweenChannel
+	from, to   float64
+	duration   time.Duration
+	easing     Easing
+	elapsed    time.Duration
+	completed  bool
+	overshoot  time.Duration
+	onComplete func()
+}
+
+// NewTween returns a tween of the channel from one value to another over duration
+func NewTween(channel TweenChannel, from, to float64, duration time.Duration, easing Easing) *Tween {
+	return &Tween{channel: channel, from: from, to: to, duration: duration, easing: easing}
+}
+
+// OnComplete calls f when the tween completes
+func (tw *Tween) OnComplete(f func()) *Tween {
+	tw.onComplete = f
+	return tw
+}
+
+// Step moves the channel on by dt. A tween with no duration goes straight to its end.
+func (tw *Tween) Step(t *Transform, dt time.Duration) bool {
+	if tw.completed {
+		tw.overshoot = dt
+		return true
+	}
+	tw.elapsed += dt
+	progress := 1.0
+	if tw.elapsed < tw.duration {
+		progress = float64(tw.elapsed) / float64(tw.duration)
+	}
+	t.set(tw.channel, tw.from+(tw.to-tw.from)*tw.easing(progress))
+	if progress < 1 {
+		return false
+	}
+	tw.overshoot = tw.elapsed - max(tw.duration, 0)
+	tw.elapsed = max(tw.duration, 0)
+	tw.completed = true
+	if tw.onComplete != nil {
+		tw.onComplete()
+	}
+	return true
+}
+
+func (tw *Tween) Overshoot() time.Duration {
+	return tw.overshoot
+}
+
+// TweenGroup plays tweens one after another or all together
+type TweenGroup struct {
+	tweens     []Tweener
+	parallel   bool
+	done       []bool // tweens of a parallel group that have completed
+	current    int    // the tween playing in a sequence
+	onComplete func()
+	completed  bool
+	overshoot  time.Duration
+}
+
+// Sequence returns a group playing the tweens one after another
+func Sequence(tweens ...Tweener) *TweenGroup {
+	return &TweenGroup{tweens: tweens}
+}
+
+// Parallel returns a group playing the tweens together, it completes when they all have
+func Parallel(tweens ...Tweener) *TweenGroup {
+	return &TweenGroup{tweens: tweens, parallel: true, done: make([]bool, len(tweens))}
+}
+
+// OnComplete calls f when the group completes
+func (g *TweenGroup) OnComplete(f func()) *TweenGroup {
+	g.onComplete = f
+	return g
+}
+
+func (g *TweenGroup) Step(t *Transform, dt time.Duration) bool {
+	if g.completed {
+		g.overshoot = dt
+		return true
+	}
+	if g.parallel {
+		// what is left over is the least left over by the tweens stepped, 0 from one still playing
+		all := true
+		g.overshoot = dt
+		for i, tw := range g.tweens {
+			if !g.done[i] {
+				g.done[i] = tw.Step(t, dt)
+				g.overshoot = min(g.overshoot, tw.Overshoot())
+			}
+			all = all && g.done[i]
+		}
+		g.completed = all
+	} else {
+		// what is left of the step when a tween completes goes on into the next
+		for g.current < len(g.tweens) && g.tweens[g.current].Step(t, dt) {
+			dt = g.tweens[g.current].Overshoot()
+			g.current++
+		}
+		g.completed = g.current >= len(g.tweens)
+		g.overshoot = dt
+	}
+	if g.completed && g.onComplete != nil {
+		g.onComplete()
+	}
+	return g.completed
+}
+
+func (g *TweenGroup) Overshoot() time.Duration {
+	if !g.completed {
+		return 0
+	}
+	return g.overshoot
+}
+
+// Delay waits for d, to hold off the next tween of a sequence
+func Delay(d time.Duration) *Tween {
+	return &Tween{channel: channelNone, duration: d, easing: Linear}
+}
+
+// MoveTween moves a transform from one point to another
+func MoveTween(fromX, fromY, toX, toY float64, duration time.Duration, easing Easing) *TweenGroup {
+	return Parallel(
+		NewTween(ChannelX, fromX, toX, duration, easing),
+		NewTween(ChannelY, fromY, toY, duration, easing),
+	)
+}
+
+// VanishTween shrinks, turns and fades a transform away
+func VanishTween(duration time.Duration) *TweenGroup {
+	return Parallel(
+		NewTween(ChannelScale, 1, 0, duration, EaseInQuad),
+		NewTween(ChannelRotation, 0, math.Pi/2, duration, Linear),
+		NewTween(ChannelAlpha, 1, 0, duration, Linear),
+	)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEasing(t *testing.T) {
+	easings := []struct {
+		name   string
+		easing Easing
+		half   float64
+	}{
+		{"Linear", Linear, 0.5},
+		{"EaseInQuad", EaseInQuad, 0.25},
+		{"EaseOutQuad", EaseOutQuad, 0.75},
+		{"EaseInOutQuad", EaseInOutQuad, 0.5},
+		{"EaseOutBack", EaseOutBack, 1.0876975},
+	}
+	for _, tt := range easings {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.easing(0); math.Abs(got) > 1e-9 {
+				t.Errorf("%s(0) = %v, want 0", tt.name, got)
+			}
+			if got := tt.easing(1); math.Abs(got-1) > 1e-9 {
+				t.Errorf("%s(1) = %v, want 1", tt.name, got)
+			}
+			if got := tt.easing(0.5); math.Abs(got-tt.half) > 1e-6 {
+				t.Errorf("%s(0.5) = %v, want %v", tt.name, got, tt.half)
+			}
+		})
+	}
+}
+
+func TestTweenStep(t *testing.T) {
+	completed := 0
+	tw := NewTween(ChannelX, 10, 50, 4*tick, Linear).OnComplete(func() { completed++ })
+	tr := NewTransform(10, 0)
+	for i, want := range []float64{20, 30, 40, 50} {
+		done := tw.Step(&tr, tick)
+		if tr.x != want || done != (i == 3) {
+			t.Errorf("step %d: x = %v, done = %v, want %v, %v", i+1, tr.x, done, want, i == 3)
+		}
+	}
+	if !tw.Step(&tr, tick) || tr.x != 50 || completed != 1 {
+		t.Errorf("after completing: x = %v, completed %d times, want 50 and once", tr.x, completed)
+	}
+}
+
+func TestTweenSequence(t *testing.T) {
+	completed := false
+	seq := Sequence(
+		Delay(2*tick),
+		NewTween(ChannelAlpha, 1, 0, 2*tick, Linear),
+		NewTween(ChannelScale, 1, 3, tick, Linear),
+	).OnComplete(func() { completed = true })
+	tr := NewTransform(0, 0)
+	want := []struct{ alpha, scale float64 }{{1, 1}, {1, 1}, {0.5, 1}, {0, 1}, {0, 3}}
+	for i, w := range want {
+		done := seq.Step(&tr, tick)
+		if tr.alpha != w.alpha || tr.scale != w.scale {
+			t.Errorf("step %d: alpha %v scale %v, want %v %v", i+1, tr.alpha, tr.scale, w.alpha, w.scale)
+		}
+		if done != (i == len(want)-1) || completed != done {
+			t.Errorf("step %d: done = %v, completed = %v", i+1, done, completed)
+		}
+	}
+}
+
+func TestTweenParallel(t *testing.T) {
+	completed := 0
+	group := Parallel(
+		NewTween(ChannelX, 0, 10, 2*tick, Linear),
+		NewTween(ChannelRotation, 0, 4, 4*tick, Linear),
+	).OnComplete(func() { completed++ })
+	tr := NewTransform(0, 0)
+	group.Step(&tr, tick)
+	if tr.x != 5 || tr.rotation != 1 {
+		t.Errorf("after one step x %v rotation %v, want 5 1", tr.x, tr.rotation)
+	}
+	for range 2 {
+		if group.Step(&tr, tick) {
+			t.Error("Parallel completed before its longest tween")
+		}
+	}
+	if !group.Step(&tr, tick) || tr.x != 10 || tr.rotation != 4 {
+		t.Errorf("at the end x %v rotation %v, want 10 4", tr.x, tr.rotation)
+	}
+	group.Step(&tr, tick)
+	if completed != 1 {
+		t.Errorf("completed %d times, want once", completed)
+	}
+}
+
+func TestTweenLongStep(t *testing.T) {
+	tw := NewTween(ChannelY, 0, 100, 50*time.Millisecond, EaseOutQuad)
+	tr := NewTransform(0, 0)
+	if !tw.Step(&tr, time.Second) || tr.y != 100 {
+		t.Errorf("a step longer than the tween left y at %v and done = false, want 100 and done", tr.y)
+	}
+	if tw.Step(&tr, 0); tr.y != 100 {
+		t.Errorf("stepping a completed tween moved y to %v", tr.y)
+	}
+}
+
+func TestTweenNoDuration(t *testing.T) {
+	completed := false
+	tw := NewTween(ChannelAlpha, 1, 0, 0, EaseOutBack).OnComplete(func() { completed = true })
+	tr := NewTransform(0, 0)
+	if !tw.Step(&tr, 0) || tr.alpha != 0 || !completed {
+		t.Errorf("a tween with no duration left alpha at %v, completed = %v, want 0 and true", tr.alpha, completed)
+	}
+}
+
+func TestTweenSequenceCarriesOvershoot(t *testing.T) {
+	seq := Sequence(
+		NewTween(ChannelX, 0, 10, 3*tick, Linear),
+		NewTween(ChannelX, 10, 30, 2*tick, Linear),
+		NewTween(ChannelY, 0, 0, 0, Linear),
+		NewTween(ChannelY, 0, 40, 4*tick, Linear),
+	)
+	tr := NewTransform(0, 0)
+	// two ticks go past the end of the first tween by one, which goes on into the second
+	seq.Step(&tr, 2*tick)
+	seq.Step(&tr, 2*tick)
+	if tr.x != 20 {
+		t.Errorf("x = %v, want 20 with the tick left over carried into the second tween", tr.x)
+	}
+	// one tick finishes the second, the tween with no duration takes none and the last gets two
+	if seq.Step(&tr, 3*tick) || tr.x != 30 || tr.y != 20 {
+		t.Errorf("x = %v, y = %v, want 30 and 20", tr.x, tr.y)
+	}
+	if !seq.Step(&tr, 3*tick) || seq.Overshoot() != tick {
+		t.Errorf("Overshoot() = %v once completed, want %v", seq.Overshoot(), tick)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+// Viewport maps a grid of cells in the world onto a region of the screen. The world may
+// be taller than the region, in which case the viewport shows a window of rows that can
+// be scrolled, as the vi scroll commands do.
+type Viewport struct {
+	cellSize    int
+	columns     int // width of the world in cells
+	rows        int // height of the world in cells
+	left        int // screen x of the leftmost column
+	top         int // screen y of the first visible row
+	visibleRows int
+	firstRow    int // world row shown at the top of the viewport
+}
+
+// NewViewport creates a viewport showing visibleRows rows of a world with its top left at left, top on the screen.
+func NewViewport(columns, rows, cellSize, left, top, visibleRows int) Viewport {
+	return Viewport{
+		cellSize:    cellSize,
+		columns:     columns,
+		rows:        rows,
+		left:        left,
+		top:         top,
+		visibleRows: min(visibleRows, rows),
+	}
+}
+
+// newCenteredViewport creates a viewport that shows the whole world centered on the screen.
+func newCenteredViewport(columns, rows, cellSize int) Viewport {
+	return NewViewport(columns, rows, cellSize,
+		(screenWidth-cellSize*columns)/2,
+		(screenHeight-cellSize*rows)/2,
+		rows)
+}
+
+// CellToScreen converts the coordinates of a cell in the world to the screen position of its top left corner.
+func (v Viewport) CellToScreen(c Coord) Coord {
+	return Coord{
+		v.left + c.x*v.cellSize,
+		v.top + (c.y-v.firstRow)*v.cellSize,
+	}
+}
+
+// ScreenToCell converts a screen position to the cell shown there. It returns false if
+// the position is outside the viewport.
+func (v Viewport) ScreenToCell(p Coord) (Coord, bool) {
+	if p.x < v.left || p.y < v.top {
+		return Coord{-1, -1}, false
+	}
+	c := Coord{(p.x - v.left) / v.cellSize, (p.y-v.top)/v.cellSize + v.firstRow}
+	if c.x >= v.columns || c.y > v.LastVisibleRow() {
+		return Coord{-1, -1}, false
+	}
+	return c, true
+}
+
+// FirstVisibleRow returns the world row at the top of the viewport.
+func (v Viewport) FirstVisibleRow() int {
+	return v.firstRow
+}
+
+// LastVisibleRow returns the world row at the bottom of the viewport.
+func (v Viewport) LastVisibleRow() int {
+	return v.firstRow + v.visibleRows - 1
+}
+
+// IsRowVisible returns true if the world row is shown in the viewport.
+func (v Viewport) IsRowVisible(row int) bool {
+	return row >= v.firstRow && row <= v.LastVisibleRow()
+}
+
+// ScrollTo shows the world from firstRow, limited so the viewport never goes past the world.
+func (v *Viewport) ScrollTo(firstRow int) {
+	v.firstRow = limitToRange(firstRow, 0, v.rows-v.visibleRows)
+}
+
+// ScrollBy scrolls the viewport down by n rows, or up when n is negative.
+func (v *Viewport) ScrollBy(n int) {
+	v.ScrollTo(v.firstRow + n)
+}
+
+// ShowRowAtTop scrolls so that row is at the top of the viewport, as zt does.
+func (v *Viewport) ShowRowAtTop(row int) {
+	v.ScrollTo(row)
+}
+
+// ShowRowAtCenter scrolls so that row is in the middle of the viewport, as zz does.
+func (v *Viewport) ShowRowAtCenter(row int) {
+	v.ScrollTo(row - v.visibleRows/2)
+}
+
+// ShowRowAtBottom scrolls so that row is at the bottom of the viewport, as zb does.
+func (v *Viewport) ShowRowAtBottom(row int) {
+	v.ScrollTo(row - v.visibleRows + 1)
+}
+
+// KeepRowVisible scrolls as little as possible so that row is shown.
+func (v *Viewport) KeepRowVisible(row int) {
+	if row < v.firstRow {
+		v.ScrollTo(row)
+	} else if row > v.LastVisibleRow() {
+		v.ShowRowAtBottom(row)
+	}
+}
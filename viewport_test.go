@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestViewportCellToScreen(t *testing.T) {
+	v := NewViewport(8, 60, 50, 100, 25, 11)
+	tests := []struct {
+		firstRow int
+		cell     Coord
+		want     Coord
+	}{
+		{0, Coord{0, 0}, Coord{100, 25}},
+		{0, Coord{2, 3}, Coord{200, 175}},
+		{10, Coord{0, 10}, Coord{100, 25}},
+		{10, Coord{1, 12}, Coord{150, 125}},
+		{10, Coord{0, 5}, Coord{100, -225}}, // rows above the viewport are off the screen
+	}
+
+	for _, tt := range tests {
+		v.ScrollTo(tt.firstRow)
+		got := v.CellToScreen(tt.cell)
+		if got != tt.want {
+			t.Errorf("CellToScreen(%v) at row %d = %v; want %v", tt.cell, tt.firstRow, got, tt.want)
+		}
+		if v.IsRowVisible(tt.cell.y) {
+			back, ok := v.ScreenToCell(got)
+			if !ok || back != tt.cell {
+				t.Errorf("ScreenToCell(%v) = %v, %v; want %v", got, back, ok, tt.cell)
+			}
+		}
+	}
+}
+
+func TestViewportScreenToCell(t *testing.T) {
+	v := NewViewport(8, 60, 50, 100, 25, 11)
+	v.ScrollTo(20)
+	tests := []struct {
+		point Coord
+		want  Coord
+		ok    bool
+	}{
+		{Coord{100, 25}, Coord{0, 20}, true},
+		{Coord{149, 74}, Coord{0, 20}, true},
+		{Coord{150, 75}, Coord{1, 21}, true},
+		{Coord{499, 574}, Coord{7, 30}, true},
+		{Coord{99, 25}, Coord{-1, -1}, false},
+		{Coord{500, 25}, Coord{-1, -1}, false},
+		{Coord{100, 575}, Coord{-1, -1}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := v.ScreenToCell(tt.point)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ScreenToCell(%v) = %v, %v; want %v, %v", tt.point, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestViewportScroll(t *testing.T) {
+	v := NewViewport(8, 60, 50, 0, 0, 11)
+	tests := []struct {
+		name      string
+		start     int
+		scroll    func(v *Viewport)
+		wantFirst int
+	}{
+		{"Scroll down", 0, func(v *Viewport) { v.ScrollBy(5) }, 5},
+		{"Scroll up past the top", 0, func(v *Viewport) { v.ScrollBy(-5) }, 0},
+		{"Scroll down past the bottom", 0, func(v *Viewport) { v.ScrollBy(100) }, 49},
+		{"zt", 0, func(v *Viewport) { v.ShowRowAtTop(30) }, 30},
+		{"zz", 0, func(v *Viewport) { v.ShowRowAtCenter(30) }, 25},
+		{"zb", 0, func(v *Viewport) { v.ShowRowAtBottom(30) }, 20},
+		{"zz near the top", 20, func(v *Viewport) { v.ShowRowAtCenter(2) }, 0},
+		{"zt near the bottom", 0, func(v *Viewport) { v.ShowRowAtTop(55) }, 49},
+		{"Keep visible below", 0, func(v *Viewport) { v.KeepRowVisible(40) }, 30},
+		{"Keep visible above", 20, func(v *Viewport) { v.KeepRowVisible(10) }, 10},
+		{"Keep visible when already visible", 20, func(v *Viewport) { v.KeepRowVisible(25) }, 20},
+	}
+
+	for _, tt := range tests {
+		v.ScrollTo(tt.start)
+		tt.scroll(&v)
+		if v.FirstVisibleRow() != tt.wantFirst {
+			t.Errorf("%s: first row = %d; want %d", tt.name, v.FirstVisibleRow(), tt.wantFirst)
+		}
+	}
+}
+
+func TestCenteredViewportMatchesGemBoard(t *testing.T) {
+	// the gems board was drawn centered on the screen before viewports existed
+	v := newCenteredViewport(8, 11, 50)
+	if got := v.CellToScreen(Coord{0, 0}); got != (Coord{200, 25}) {
+		t.Errorf("top left = %v; want {200 25}", got)
+	}
+	if got := v.CellToScreen(Coord{7, 10}); got != (Coord{550, 525}) {
+		t.Errorf("bottom right = %v; want {550 525}", got)
+	}
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"image/color"
 	_ "image/png"
 	"log"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/exp/constraints"
@@ -25,14 +27,36 @@ import (
 const (
 	screenWidth  = 800
 	screenHeight = 600
+	maxStars     = 3 // the best rating for a win
 	version      = "Viple 0.1"
 )
 
 // Level interface
 type Level interface {
-	Draw(screen *ebiten.Image, frameCount int)
+	Draw(screen *ebiten.Image, now time.Duration)
 	Initialize(id LevelID)
-	Update(frameCount int) (bool, error)
+	Update(clock Clock) (bool, error)
+}
+
+// FailableLevel is a level that can be lost, such as by running out of moves
+type FailableLevel interface {
+	Failed() (reason string, failed bool)
+}
+
+// ScoredLevel is a level that keeps a score, recorded as the best for the level when it is beaten
+type ScoredLevel interface {
+	Score() int
+}
+
+// VersusLevel is a level played between players, it ends when one of them wins
+type VersusLevel interface {
+	Winner() string // the player who won
+}
+
+// RatedLevel is a level that rates a win with stars
+type RatedLevel interface {
+	Stars() int        // 1 to 3, or 0 if the level is not rated
+	Remaining() string // the budget left, such as the moves
 }
 
 type LevelID int
@@ -41,15 +65,45 @@ type LevelID int
 const (
 	LevelIdFlappy = iota
 	LevelIdBricksHL
+	LevelIdBricksHJKL
 	LevelIdSnake
 	LevelIdInsertMode
 	LevelIdGemsDD
 	LevelIdGemsVM
+	LevelIdGemsReplace
+	LevelIdGemsJoin
+	LevelIdSubstitute
+	LevelIdMarks
+	LevelIdBrackets
+	LevelIdScroll
 	LevelIdGemsEnd
-	// deprecated levels below
-	LevelIdBricksHJKL
+	// extra levels follow the learning levels and are played from the level select
+	LevelIdSnakeDuel
+	LevelIdFlappyGravity
+	LevelIdSnakeWrap
 )
 
+// levelNames name the levels in saved data, where the LevelIDs would change as levels are added
+var levelNames = map[LevelID]string{
+	LevelIdFlappy:        "flappy",
+	LevelIdBricksHL:      "bricks_hl",
+	LevelIdBricksHJKL:    "bricks_hjkl",
+	LevelIdSnake:         "snake",
+	LevelIdInsertMode:    "insert_mode",
+	LevelIdGemsDD:        "gems_dd",
+	LevelIdGemsVM:        "gems_visual",
+	LevelIdGemsReplace:   "gems_replace",
+	LevelIdGemsJoin:      "gems_join",
+	LevelIdSubstitute:    "substitute",
+	LevelIdMarks:         "marks",
+	LevelIdBrackets:      "brackets",
+	LevelIdScroll:        "scroll",
+	LevelIdGemsEnd:       "gems_challenge",
+	LevelIdSnakeDuel:     "snake_duel",
+	LevelIdFlappyGravity: "flappy_gravity",
+	LevelIdSnakeWrap:     "snake_wrap",
+}
+
 // LevelMode is the mode of the level
 type LevelMode int
 
@@ -58,6 +112,8 @@ const (
 	PlayMode
 	OutroMode
 	QuitMode
+	FailedMode
+	SelectMode
 )
 
 // VIModes are modes matching the modes in the vi editor
@@ -67,18 +123,22 @@ const (
 	NormalMode = iota
 	VisualMode
 	InsertMode
+	CommandLineMode
+	ReplaceMode
 )
 
 var (
-	rng        *rand.Rand
-	globalKeys []ebiten.Key
+	rng         *rand.Rand
+	globalChars []rune
+	globalKeys  []ebiten.Key
 )
 
 type Game struct {
+	best         BestScores
+	clock        *GameClock
 	currentLevel LevelID
 	curLevel     Level
 	mode         LevelMode
-	frameCount   int
 	lastUpdate   time.Time
 	ui           *ebitenui.UI
 	uiRes        *uiResources
@@ -90,7 +150,9 @@ type Number interface {
 
 func main() {
 	var seed int
+	var speed float64
 	flag.IntVar(&seed, "seed", 0, "Seed for random number generation")
+	flag.Float64Var(&speed, "speed", 1, "Speed of game time, below 1 for slow motion")
 	flag.Parse()
 	seedRNG(int64(seed))
 
@@ -98,7 +160,7 @@ func main() {
 	ebiten.SetWindowTitle(version)
 	PlaySoundForever(musicOgg)
 
-	if err := ebiten.RunGame(newGame()); err != nil {
+	if err := ebiten.RunGame(newGame(speed)); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -109,10 +171,10 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		screen.Fill(darkButter)
 		os.Exit(0)
 	} else {
-		g.curLevel.Draw(screen, g.frameCount)
+		g.curLevel.Draw(screen, g.clock.Now())
 
 		// the UI
-		if g.mode == IntroMode || g.mode == OutroMode {
+		if g.mode != PlayMode {
 			g.ui.Draw(screen)
 		}
 	}
@@ -137,11 +199,18 @@ func (g *Game) Update() error {
 	// }
 	g.lastUpdate = now
 
-	// increment the frame count
-	g.frameCount++
+	// game time stands still while a dialog is shown
+	if g.mode == PlayMode {
+		g.clock.Resume()
+	} else {
+		g.clock.Pause()
+	}
+	g.clock.Tick()
 
 	// save the keys that were pressed in this frame
 	globalKeys = inpututil.AppendJustPressedKeys(globalKeys)
+	// save the characters typed in this frame, used for keys such as : and % that need shift
+	globalChars = ebiten.AppendInputChars(globalChars[:0])
 	if isQuitKeyPressed() {
 		// commenting out quit for WASM builds
 		//g.mode = QuitMode
@@ -160,12 +229,23 @@ func (g *Game) Update() error {
 		removeDuplicatesOf(&globalKeys, ebiten.KeyJ)
 		removeDuplicatesOf(&globalKeys, ebiten.KeyK)
 		removeDuplicatesOf(&globalKeys, ebiten.KeyL)
-		levelOver, err = g.curLevel.Update(g.frameCount)
+		levelOver, err = g.curLevel.Update(g.clock)
 		if levelOver {
 			PlaySound(winOgg)
 			g.mode = OutroMode
-			showOutroDialog(g)
+			showOutroDialog(g, recordScore(g))
+		} else if fl, ok := g.curLevel.(FailableLevel); ok {
+			if reason, failed := fl.Failed(); failed {
+				PlaySound(failOgg)
+				g.mode = FailedMode
+				showFailedDialog(g, reason, recordScore(g))
+			}
 		}
+	case FailedMode:
+		g.ui.Update()
+		checkForKeystroke(ebiten.KeyEnter, func() { retryLevel(g) })
+	case SelectMode:
+		g.ui.Update()
 	case QuitMode:
 		//
 	}
@@ -195,13 +275,44 @@ func gameDimensions() (width int, height int) {
 	return screenWidth, screenHeight
 }
 
+// retryLevel plays the current level again after it was lost
+func retryLevel(g *Game) {
+	clearKeystrokes()
+	g.curLevel.Initialize(g.currentLevel)
+	g.mode = PlayMode
+}
+
+// startLevel shows the intro of a level chosen in the level select
+func startLevel(g *Game, id LevelID) {
+	clearKeystrokes()
+	g.currentLevel = id
+	g.curLevel = newLevel(id)
+	g.curLevel.Initialize(id)
+	g.mode = IntroMode
+	showIntroDialog(g)
+}
+
+// recordScore records the score of a scored level as the best for the level if it beats it,
+// returning true if it does
+func recordScore(g *Game) bool {
+	if sl, ok := g.curLevel.(ScoredLevel); ok && g.best.Record(g.currentLevel, sl.Score()) {
+		g.best.Save()
+		return true
+	}
+	return false
+}
+
 // advance to the next mode
 func advanceLevelMode(g *Game) {
+	if g.mode == OutroMode && g.currentLevel >= LevelIdGemsEnd {
+		// after the last learning level and after a duel the player chooses what to play next
+		clearKeystrokes()
+		showLevelSelectDialog(g)
+		return
+	}
 	if g.mode == OutroMode {
 		// advance to next Level if current level has been won
-		if g.currentLevel != LevelIdGemsEnd {
-			g.currentLevel += 1
-		}
+		g.currentLevel += 1
 		clearKeystrokes()
 		globalKeys = globalKeys[:0] // clear the keys
 	}
@@ -214,30 +325,53 @@ func advanceLevelMode(g *Game) {
 		log.Println("Closing UI when UI is not showing?")
 	}
 	if g.mode == IntroMode {
-		switch g.currentLevel {
-		case LevelIdBricksHL:
-			g.curLevel = Level(&LevelBricksHL{})
-		// case LevelIdBricksHJKL:
-		// 	g.curLevel = Level(&LevelBricksHL{})
-		case LevelIdFlappy:
-			g.curLevel = Level(&LevelFlappy{})
-		case LevelIdGemsVM:
-			g.curLevel = Level(&LevelGems{})
-		case LevelIdGemsEnd:
-			g.curLevel = Level(&LevelGems{})
-		case LevelIdGemsDD:
-			g.curLevel = Level(&LevelGems{})
-		case LevelIdSnake:
-			g.curLevel = Level(&LevelSnake{})
-		case LevelIdInsertMode:
-			g.curLevel = Level(&LevelSnake{})
-		default:
-			log.Fatal("Invalid level")
-		}
+		g.curLevel = newLevel(g.currentLevel)
 		g.curLevel.Initialize(g.currentLevel)
 	}
 }
 
+// newLevel creates the level for a LevelID
+func newLevel(id LevelID) Level {
+	switch id {
+	case LevelIdBricksHL:
+		return Level(&LevelBricksHL{})
+	case LevelIdBricksHJKL:
+		return Level(&LevelBricksHL{})
+	case LevelIdFlappy:
+		return Level(&LevelFlappy{})
+	case LevelIdFlappyGravity:
+		return Level(&LevelFlappy{})
+	case LevelIdGemsVM:
+		return Level(&LevelGems{})
+	case LevelIdGemsEnd:
+		return Level(&LevelGems{})
+	case LevelIdGemsDD:
+		return Level(&LevelGems{})
+	case LevelIdGemsReplace:
+		return Level(&LevelGems{})
+	case LevelIdGemsJoin:
+		return Level(&LevelGems{})
+	case LevelIdSnake:
+		return Level(&LevelSnake{})
+	case LevelIdInsertMode:
+		return Level(&LevelSnake{})
+	case LevelIdSnakeDuel:
+		return Level(&LevelSnake{})
+	case LevelIdSnakeWrap:
+		return Level(&LevelSnake{})
+	case LevelIdSubstitute:
+		return Level(&LevelSubstitute{})
+	case LevelIdMarks:
+		return Level(&LevelMarks{})
+	case LevelIdBrackets:
+		return Level(&LevelBrackets{})
+	case LevelIdScroll:
+		return Level(&LevelScroll{})
+	}
+	log.Fatal("Invalid level")
+	return nil
+}
+
 func clearKeystrokes() {
 	globalKeys = globalKeys[:0]
 }
@@ -308,8 +442,10 @@ func newSeparator(res *uiResources, ld interface{}) widget.PreferredSizeLocateab
 	return c
 }
 
-func newGame() *Game {
-	g := Game{}
+// newGame returns a game starting at the first level, with game time passing at speed
+func newGame(speed float64) *Game {
+	g := Game{best: loadBestScores(), clock: NewGameClock()}
+	g.clock.SetScale(speed)
 
 	g.mode = IntroMode
 	g.curLevel = Level(&LevelFlappy{})
@@ -435,10 +571,10 @@ func showIntroDialog(g *Game) {
 		}),
 	)
 	innerContainer.AddChild(b)
-
+	innerContainer.AddChild(newDialogButton(g, "Level Select", func() { showLevelSelectDialog(g) }))
 }
 
-func showOutroDialog(g *Game) {
+func showOutroDialog(g *Game, newBest bool) {
 	// This loads a font and creates a font face.
 	ttfFont, err := truetype.Parse(goregular.TTF)
 	if err != nil {
@@ -467,11 +603,34 @@ func showOutroDialog(g *Game) {
 	)
 	g.ui.Container.AddChild(innerContainer)
 
+	title := "Level Completed!"
+	if vl, ok := g.curLevel.(VersusLevel); ok {
+		title = vl.Winner() + " wins!"
+	}
 	titleText := widget.NewText(
-		widget.TextOpts.Text("Level Completed!", titleFace, color.White),
+		widget.TextOpts.Text(title, titleFace, color.White),
 	)
 	innerContainer.AddChild(titleText)
 
+	if rl, ok := g.curLevel.(RatedLevel); ok && rl.Stars() > 0 {
+		textFace := truetype.NewFace(ttfFont, &truetype.Options{
+			Size: 20,
+		})
+		starsText := widget.NewText(
+			widget.TextOpts.Text(ratingText(rl), textFace, dlgText),
+		)
+		innerContainer.AddChild(starsText)
+	}
+
+	if sl, ok := g.curLevel.(ScoredLevel); ok {
+		textFace := truetype.NewFace(ttfFont, &truetype.Options{
+			Size: 20,
+		})
+		innerContainer.AddChild(widget.NewText(
+			widget.TextOpts.Text(scoreText(sl.Score(), g.best[g.currentLevel], newBest), textFace, dlgText),
+		))
+	}
+
 	innerContainer.AddChild(newSeparator(g.uiRes, widget.RowLayoutData{
 		Stretch: true,
 	}))
@@ -489,6 +648,7 @@ func showOutroDialog(g *Game) {
 		}),
 	)
 	innerContainer.AddChild(b)
+	innerContainer.AddChild(newDialogButton(g, "Level Select", func() { showLevelSelectDialog(g) }))
 }
 
 // tail returns the last n elements of a slice.
@@ -498,3 +658,129 @@ func tail[T comparable](slice []T, n int) []T {
 	}
 	return slice[len(slice)-n:]
 }
+
+// ratingText shows the stars for a win as filled and empty stars, and the budget left
+func ratingText(rl RatedLevel) string {
+	stars := rl.Stars()
+	return fmt.Sprintf("%s%s  %d of %d stars\n%s left",
+		strings.Repeat("*", stars), strings.Repeat("-", maxStars-stars), stars, maxStars, rl.Remaining())
+}
+
+// scoreText reports a score and the best score for the level
+func scoreText(score, best int, newBest bool) string {
+	if newBest {
+		return fmt.Sprintf("Score %d, a new best!", score)
+	}
+	return fmt.Sprintf("Score %d, best %d", score, best)
+}
+
+// newDialogButton creates a button for a dialog that calls clicked when it is pressed
+func newDialogButton(g *Game, label string, clicked func()) *widget.Button {
+	return widget.NewButton(
+		widget.ButtonOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Stretch: true,
+		})),
+		widget.ButtonOpts.Image(g.uiRes.button.image),
+		widget.ButtonOpts.Text(label, g.uiRes.button.face, g.uiRes.button.text),
+		widget.ButtonOpts.TextPadding(g.uiRes.button.padding),
+		widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
+			clicked()
+		}),
+	)
+}
+
+func showFailedDialog(g *Game, reason string, newBest bool) {
+	// This loads a font and creates a font face.
+	ttfFont, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		log.Fatal("Error Parsing Font", err)
+	}
+	// release resources
+	g.ui.Container.RemoveChildren()
+
+	textFace := truetype.NewFace(ttfFont, &truetype.Options{
+		Size: 20,
+	})
+	titleFace := truetype.NewFace(ttfFont, &truetype.Options{
+		Size: 32,
+	})
+	innerContainer := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(dlgBackground)),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(1),
+			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(30)),
+			widget.GridLayoutOpts.Spacing(20, 10),
+			widget.GridLayoutOpts.Stretch([]bool{true, false}, []bool{false, true}),
+		)),
+	)
+	g.ui.Container.AddChild(innerContainer)
+
+	titleText := widget.NewText(
+		widget.TextOpts.Text("Level Failed", titleFace, dlgText),
+	)
+	innerContainer.AddChild(titleText)
+
+	reasonText := widget.NewText(
+		widget.TextOpts.Text(reason, textFace, dlgText),
+	)
+	innerContainer.AddChild(reasonText)
+
+	if sl, ok := g.curLevel.(ScoredLevel); ok {
+		innerContainer.AddChild(widget.NewText(
+			widget.TextOpts.Text(scoreText(sl.Score(), g.best[g.currentLevel], newBest), textFace, dlgText),
+		))
+	}
+
+	innerContainer.AddChild(newSeparator(g.uiRes, widget.RowLayoutData{
+		Stretch: true,
+	}))
+
+	innerContainer.AddChild(newDialogButton(g, "Retry", func() { retryLevel(g) }))
+	innerContainer.AddChild(newDialogButton(g, "Level Select", func() { showLevelSelectDialog(g) }))
+}
+
+// showLevelSelectDialog lists the levels with their best scores, a level is played by choosing it
+func showLevelSelectDialog(g *Game) {
+	// This loads a font and creates a font face.
+	ttfFont, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		log.Fatal("Error Parsing Font", err)
+	}
+	g.mode = SelectMode
+	// release resources
+	g.ui.Container.RemoveChildren()
+
+	titleFace := truetype.NewFace(ttfFont, &truetype.Options{
+		Size: 32,
+	})
+	innerContainer := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(dlgBackground)),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(1),
+			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(30)),
+			widget.GridLayoutOpts.Spacing(20, 10),
+			widget.GridLayoutOpts.Stretch([]bool{true}, nil),
+		)),
+	)
+	g.ui.Container.AddChild(innerContainer)
+
+	innerContainer.AddChild(widget.NewText(
+		widget.TextOpts.Text("Select a Level", titleFace, dlgText),
+	))
+
+	levels := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(3),
+			widget.GridLayoutOpts.Spacing(10, 10),
+			widget.GridLayoutOpts.Stretch([]bool{true, true, true}, nil),
+		)),
+	)
+	innerContainer.AddChild(levels)
+	for id := LevelID(LevelIdFlappy); id <= LevelIdSnakeWrap; id++ {
+		label := TitleText(id)
+		if best, ok := g.best[id]; ok {
+			label = fmt.Sprintf("%s %d", label, best)
+		}
+		levels.AddChild(newDialogButton(g, label, func() { startLevel(g, id) }))
+	}
+}